@@ -7,6 +7,7 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"log"
 	"math"
 	"os"
 	"path/filepath"
@@ -16,6 +17,7 @@ import (
 
 	"github.com/pfandzelter/bibclean/pkg/bbl"
 	"github.com/pfandzelter/bibclean/pkg/bibtex"
+	"github.com/pfandzelter/bibclean/pkg/budget"
 	"github.com/pfandzelter/bibclean/pkg/merge"
 )
 
@@ -72,6 +74,37 @@ func (a *additionalFields) Set(v string) error {
 	return nil
 }
 
+type fileList []string
+
+func (f *fileList) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *fileList) Set(v string) error {
+	*f = append(*f, v)
+
+	return nil
+}
+
+type fieldList map[string]struct{}
+
+func (f *fieldList) String() string {
+	s := make([]string, 0, len(*f))
+	for field := range *f {
+		s = append(s, field)
+	}
+	sort.Strings(s)
+	return strings.Join(s, ",")
+}
+
+func (f *fieldList) Set(v string) error {
+	for _, field := range strings.Split(v, ",") {
+		(*f)[strings.ToLower(strings.TrimSpace(field))] = struct{}{}
+	}
+
+	return nil
+}
+
 func fmtBreak(s string, n int) string {
 	if len(s) >= n {
 		return s
@@ -87,11 +120,19 @@ func fmtBreak(s string, n int) string {
 
 func main() {
 
-	var printVersion, noMerge *bool
+	var printVersion, noMerge, truncateEditors, expand, mergeDuplicates *bool
+	var duplicateThreshold *float64
+	var maxAuthors, keepAuthors *int
+	var budgetChars, budgetLines, lineWidth *int
 	var bibfile, newfile, bblfile, shorten *string
-	var defaults *string
+	var defaults, titleCase, names, venuesfile, publishersfile *string
 	var shortenBooktitle, shortenAll bool
 	var additional additionalFields = make(additionalFields)
+	var junk, keep fieldList = make(fieldList), make(fieldList)
+	var junkPolicy, sidecar *string
+	var missing *string
+	var abbrevLists fileList
+	var abbrev *string
 
 	printVersion = flag.Bool("version", false, "print bibclean version and exit")
 	bibfile = flag.String("in", "", "input bibliography file")
@@ -99,11 +140,34 @@ func main() {
 	bblfile = flag.String("bbl", "", "(optional) auxillary .bbl file to check which references have been used in the text")
 	defaults = flag.String("defaults", "acm", "(optional) default data fields, can be \"ieee\" (for IEEEtran.bst), \"acm\" (for ACM-Reference-Format.bst), or \"biblatex\" (for biblatex)")
 	shorten = flag.String("shorten", "", "(optional) level of applied title shortening to conform with IEEE citation style, can be \"publication\" (shorten only proceeding and journal titles with some common abbreviations) or \"all\" (aggressive shortening including shortening titles and author list, uses the full list of abbrevations)")
+	abbrev = flag.String("abbrev", "ieee", "(optional) abbreviations for --shorten, can be \"ieee\" (the IEEE abbreviation lists) or \"iso4\" (ISO 4 journal abbreviations, article titles are left alone)")
+	budgetChars = flag.Int("budget-chars", 0, "(optional) shorten cited entries (see --bbl) only as much as needed to fit their estimated length into this many characters: venues first, then title words, then author lists, cannot be used together with --shorten")
+	budgetLines = flag.Int("budget-lines", 0, "(optional) like --budget-chars, but the budget is in lines of --line-width characters")
+	lineWidth = flag.Int("line-width", 50, "(optional) characters per line in the bibliography for --budget-lines, about 50 for two-column layouts")
+	expand = flag.Bool("expand", false, "(optional) write abbreviated venues and title words out in full, the opposite of --shorten (which cannot be used together with it)")
+	flag.Var(&abbrevLists, "abbrev-list", "(optional) journal abbreviation list in JabRef's CSV format (\"full name;abbreviation\"), used by --shorten before the built-in abbreviations, specify as many as you like, earlier lists take precedence")
+	titleCase = flag.String("case", "", "(optional) case conversion for titles, can be \"title\" (title case), \"sentence\" (sentence case), or \"none\", defaults to title case for ieee and none for acm and biblatex (sentence case reports every word it lowercases)")
+	names = flag.String("names", "last-first", "(optional) form for author and editor names, can be \"last-first\" (\"Smith, John\"), \"first-last\" (\"John Smith\"), or \"none\" to keep names as they are")
+	maxAuthors = flag.Int("max-authors", 0, "(optional) with --shorten all or a budget, truncate author lists with more names than this, defaults to 6 for ieee and 2 otherwise")
+	keepAuthors = flag.Int("keep-authors", 0, "(optional) with --shorten all or a budget, number of names to keep when truncating author lists, defaults to 1")
+	truncateEditors = flag.Bool("truncate-editors", false, "(optional) with --shorten all, truncate editor lists as well")
+	venuesfile = flag.String("venues", "", "(optional) YAML or JSON file with additional venues (conferences and journals) and their canonical names, see pkg/bibtex/data/venues.yaml for the format")
+	publishersfile = flag.String("publishers", "", "(optional) YAML or JSON file with additional publishers and their addresses, see pkg/bibtex/data/publishers.yaml for the format")
 	noMerge = flag.Bool("no-merge", false, "(optional) disable merging repeated entries based on key. redundant values will be added as comments")
+	mergeDuplicates = flag.Bool("merge-duplicates", false, "(optional) merge entries with different keys that are the same work (see --duplicate-threshold), the keys of the merged entries are kept in an \"ids\" field")
+	duplicateThreshold = flag.Float64("duplicate-threshold", 0.9, "(optional) how similar (from 0 to 1) the titles of two entries with the same year and first author must be to report them as duplicates, entries with the same DOI or arXiv identifier are always duplicates")
+	flag.Var(&junk, "junk", "(optional) additional fields to treat as junk (like \"abstract\" or \"file\", which are junk by default), specify as many as you like or separate them with commas")
+	flag.Var(&keep, "keep", "(optional) fields that are never junk, even if they are by default, specify as many as you like or separate them with commas")
+	junkPolicy = flag.String("junk-policy", "comment", "(optional) what to do with junk fields: \"comment\" (keep them as comments above the entry), \"drop\" (remove them), or \"sidecar\" (move them to a separate file, see --sidecar)")
+	sidecar = flag.String("sidecar", "", "(optional) file for junk fields with --junk-policy sidecar, defaults to the output file with a .junk.bib extension")
+	missing = flag.String("missing", "comment", "(optional) what to do with required fields that an entry is missing: \"placeholder\" (write them as \"MISSING\"), \"comment\" (list them in a comment above the entry), \"omit\" (leave them out), or \"error\" (fail after reporting them), missing fields are always reported")
 	flag.Var(&additional, "additional", "Additional fields for entries: specify as many as you like in the form \"--additional=article:booktitle --additional=techreport:address\" (this will add a \"booktitle\" field to \"@article\" entries and an \"address\" field to \"@techreport\" entries)")
 
 	flag.Parse()
 
+	// plugins report problems they cannot fix through the log
+	log.SetFlags(0)
+
 	if *printVersion {
 		fmt.Printf("bibclean\nversion %s\nbuilt %s\ncommit %s\n", version, date, commit)
 		os.Exit(0)
@@ -122,6 +186,39 @@ func main() {
 		incorrectUse = true
 	}
 
+	// with a budget, the shortening steps are only taken where needed
+	budgeted := *budgetChars > 0 || *budgetLines > 0
+
+	if *expand && (shortenBooktitle || shortenAll || budgeted) {
+		incorrectUse = true
+	}
+
+	if *duplicateThreshold <= 0 || *duplicateThreshold > 1 {
+		incorrectUse = true
+	}
+
+	if (budgeted && (shortenBooktitle || shortenAll)) || (*budgetChars > 0 && *budgetLines > 0) || *budgetChars < 0 || *budgetLines < 0 || *lineWidth < 1 {
+		incorrectUse = true
+	}
+
+	switch *abbrev {
+	case "ieee", "iso4":
+	default:
+		incorrectUse = true
+	}
+
+	switch *junkPolicy {
+	case "comment", "drop", "sidecar":
+	default:
+		incorrectUse = true
+	}
+
+	switch *missing {
+	case bibtex.MissingPlaceholder, bibtex.MissingComment, bibtex.MissingOmit, bibtex.MissingError:
+	default:
+		incorrectUse = true
+	}
+
 	if incorrectUse {
 		flag.PrintDefaults()
 		os.Exit(1)
@@ -150,8 +247,10 @@ func main() {
 		check(err)
 	}
 
+	style := strings.ToLower(*defaults)
+
 	var e map[string][]string
-	switch strings.ToLower(*defaults) {
+	switch style {
 	case "ieee":
 		e = fields["ieee"]
 	case "acm":
@@ -171,21 +270,130 @@ func main() {
 		i++
 	}
 
+	venues := bibtex.DefaultVenues()
+
+	if *venuesfile != "" {
+		venuescontents, err := os.ReadFile(*venuesfile)
+
+		check(err)
+
+		userVenues, err := bibtex.LoadVenues(venuescontents)
+
+		check(err)
+
+		venues = append(userVenues, venues...)
+	}
+
+	publishers := bibtex.DefaultPublishers()
+
+	if *publishersfile != "" {
+		publisherscontents, err := os.ReadFile(*publishersfile)
+
+		check(err)
+
+		userPublishers, err := bibtex.LoadPublishers(publisherscontents)
+
+		check(err)
+
+		publishers = append(userPublishers, publishers...)
+	}
+
+	var lists bibtex.AbbreviationLists
+
+	for _, abbrevList := range abbrevLists {
+		abbrevcontents, err := os.ReadFile(abbrevList)
+
+		check(err)
+
+		list, err := bibtex.LoadJabRef(abbrevcontents)
+
+		check(err)
+
+		lists = append(lists, list)
+	}
+
 	plugins := []func(e bibtex.Element) bibtex.Element{
 		bibtex.CleanQuotationMarks,
-		bibtex.AddProcOf,
-		bibtex.CleanCurly,
+		bibtex.CleanNumbers,
+		bibtex.NormalizeDate(style),
+		bibtex.NormalizeMonth(style),
 		bibtex.CleanDOI,
+		bibtex.CleanURL(style),
+		bibtex.NormalizeArXiv(style),
+		bibtex.InferType(e),
+	}
+
+	// expand venues before they are matched against the venue database
+	if *expand {
+		plugins = append(plugins, bibtex.ExpandAbbreviations(lists, venues))
+	}
+
+	plugins = append(plugins,
+		bibtex.CanonicalizeVenues(venues, lists, style),
+		bibtex.AddProcOf(style),
+		bibtex.CleanCurly,
+		bibtex.EscapeSpecial,
 		bibtex.CleanPages,
-		bibtex.AddPublisherAddress,
+		bibtex.CleanISBN,
+		bibtex.CleanISSN,
+		bibtex.NormalizePublisher(publishers, style),
+		bibtex.AddPublisherAddress(publishers),
+	)
+
+	switch *names {
+	case "none":
+	case bibtex.NamesLastFirst, bibtex.NamesFirstLast:
+		plugins = append(plugins, bibtex.NormalizeNames(*names))
+	default:
+		fmt.Printf("unknown name form: %s\n", *names)
+		os.Exit(1)
+	}
+
+	if *titleCase == "" {
+		*titleCase = caseStyles[style]
+	}
+
+	switch *titleCase {
+	case "", "none":
+	case "title":
+		plugins = append(plugins, bibtex.TitleCase)
+	case "sentence":
+		plugins = append(plugins, bibtex.SentenceCase)
+	default:
+		fmt.Printf("unknown case conversion: %s\n", *titleCase)
+		os.Exit(1)
+	}
+
+	shortenVenue := bibtex.ShortenBooktitle(lists)
+	if *abbrev == "iso4" {
+		shortenVenue = bibtex.AbbreviateISO4(lists)
 	}
 
 	if shortenBooktitle {
-		plugins = append(plugins, bibtex.ShortenBooktitle)
+		plugins = append(plugins, shortenVenue)
+	}
+
+	if shortenAll || budgeted {
+		if *maxAuthors == 0 {
+			*maxAuthors = truncation[style].max
+		}
+
+		if *keepAuthors == 0 {
+			*keepAuthors = truncation[style].keep
+		}
+
+		if *keepAuthors < 1 || *keepAuthors > *maxAuthors {
+			fmt.Printf("cannot keep %d of at most %d authors\n", *keepAuthors, *maxAuthors)
+			os.Exit(1)
+		}
 	}
 
 	if shortenAll {
-		plugins = append(plugins, bibtex.ShortenAll)
+		if *abbrev == "ieee" {
+			plugins = append(plugins, bibtex.ShortenAll(lists))
+		}
+
+		plugins = append(plugins, bibtex.TruncateNames(*maxAuthors, *keepAuthors, *truncateEditors))
 	}
 
 	elements, err := bibtex.Parse(contents, &e, additional, plugins)
@@ -198,6 +406,110 @@ func main() {
 		check(err)
 	}
 
+	duplicates := merge.FindDuplicates(elements, *duplicateThreshold)
+
+	if len(duplicates) > 0 {
+		fmt.Printf("possible duplicates:\n")
+
+		for _, c := range duplicates {
+			ids := make([]string, len(c.Elements))
+			for i, element := range c.Elements {
+				ids[i] = element.ID
+			}
+
+			fmt.Printf("  %s (%s, %.2f)\n", strings.Join(ids, ", "), strings.Join(c.Reasons, ", "), c.Score)
+		}
+	}
+
+	if *mergeDuplicates {
+		var cited map[string]struct{}
+		if usebbl {
+			cited = used
+		}
+
+		// only biblatex resolves citations of merged keys through ids
+		var skipped []merge.Cluster
+		elements, skipped = merge.MergeDuplicates(elements, duplicates, cited, style == "biblatex")
+
+		for _, c := range skipped {
+			ids := make([]string, len(c.Elements))
+			for i, element := range c.Elements {
+				ids[i] = element.ID
+			}
+
+			if usebbl {
+				fmt.Printf("not merging %s: more than one of them is cited\n", strings.Join(ids, ", "))
+			} else {
+				fmt.Printf("not merging %s: need --bbl to know which of them are cited\n", strings.Join(ids, ", "))
+			}
+		}
+	}
+
+	var sidecarElements []*bibtex.Element
+
+	if *junkPolicy != "comment" {
+		for _, field := range junkFields[style] {
+			junk[field] = struct{}{}
+		}
+
+		for field := range keep {
+			delete(junk, field)
+		}
+
+		for _, element := range elements {
+			extracted := element.Extract(junk)
+			if extracted != nil && *junkPolicy == "sidecar" {
+				sidecarElements = append(sidecarElements, extracted)
+			}
+		}
+	}
+
+	if *junkPolicy == "sidecar" {
+		if *sidecar == "" {
+			*sidecar = strings.TrimSuffix(newfilePath, filepath.Ext(newfilePath)) + ".junk.bib"
+		}
+
+		slices.SortFunc(sidecarElements, func(a, b *bibtex.Element) int {
+			return cmp.Compare(strings.ToLower(a.ID), strings.ToLower(b.ID))
+		})
+
+		sidecarBuf := bytes.Buffer{}
+		for _, element := range sidecarElements {
+			fmt.Fprintf(&sidecarBuf, "%s\n\n", element)
+		}
+
+		check(os.WriteFile(*sidecar, sidecarBuf.Bytes(), 0644))
+	}
+
+	if budgeted {
+		steps := []budget.Step{
+			{Name: "venue", Apply: shortenVenue},
+		}
+
+		// as with --shorten all, ISO 4 leaves article titles alone
+		if *abbrev == "ieee" {
+			steps = append(steps, budget.Step{Name: "title words", Apply: bibtex.ShortenAll(lists)})
+		}
+
+		steps = append(steps, budget.Step{Name: "authors", Apply: bibtex.TruncateNames(*maxAuthors, *keepAuthors, *truncateEditors)})
+
+		if *budgetLines > 0 {
+			fitBudget(elements, used, usebbl, *budgetLines, *lineWidth, steps)
+		} else {
+			fitBudget(elements, used, usebbl, *budgetChars, 0, steps)
+		}
+	}
+
+	checkRequired := bibtex.CheckRequired(mandatory[style])
+
+	for _, element := range elements {
+		*element = checkRequired(*element)
+	}
+
+	if reportMissing(elements) > 0 && *missing == bibtex.MissingError {
+		os.Exit(1)
+	}
+
 	// sort types alphabetically
 	sort.Strings(types)
 
@@ -232,7 +544,7 @@ func main() {
 			fmt.Fprintf(&buf, "%% %s\n\n", fmtBreak(strings.ToUpper(t), terminalWidth-2))
 
 			for _, element := range elemUsed[t] {
-				fmt.Fprintf(&buf, "%s\n\n", element)
+				fmt.Fprintf(&buf, "%s\n\n", element.Format(*missing))
 			}
 		}
 
@@ -246,7 +558,7 @@ func main() {
 		fmt.Fprintf(&buf, "%% %s\n\n", fmtBreak(strings.ToUpper(t), terminalWidth-2))
 
 		for _, element := range elemDefault[t] {
-			fmt.Fprintf(&buf, "%s\n\n", element)
+			fmt.Fprintf(&buf, "%s\n\n", element.Format(*missing))
 		}
 	}
 
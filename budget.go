@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/pfandzelter/bibclean/pkg/bibtex"
+	"github.com/pfandzelter/bibclean/pkg/budget"
+)
+
+// fitBudget shortens the cited elements (all elements without a bbl file)
+// until they fit in the budget, which is in characters or, if width is set,
+// in lines of width characters. It prints what it had to do.
+func fitBudget(elements []*bibtex.Element, used map[string]struct{}, usebbl bool, limit int, width int, steps []budget.Step) {
+	var cited []*bibtex.Element
+
+	for _, element := range elements {
+		if _, ok := used[element.ID]; ok || !usebbl {
+			cited = append(cited, element)
+		}
+	}
+
+	measure, unit := budget.Length, "characters"
+	if width > 0 {
+		measure, unit = budget.Lines(width), "lines"
+	}
+
+	total := func() int {
+		t := 0
+		for _, element := range cited {
+			t += measure(element)
+		}
+		return t
+	}
+
+	before := total()
+
+	fmt.Printf("budget: %d %s for %d entries, estimated %d\n", limit, unit, len(cited), before)
+
+	if before <= limit {
+		return
+	}
+
+	actions, fits := budget.Fit(cited, limit, measure, steps)
+
+	for _, a := range actions {
+		fmt.Printf("  %s: %s (-%d characters)\n", a.ID, a.Step, a.Saved)
+	}
+
+	if !fits {
+		fmt.Printf("does not fit the budget, estimated %d %s after all shortening steps\n", total(), unit)
+		return
+	}
+
+	fmt.Printf("estimated %d %s after shortening %d times\n", total(), unit, len(actions))
+}
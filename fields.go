@@ -1,5 +1,53 @@
 package main
 
+// Title case conversion per style
+
+var caseStyles = map[string]string{
+	"ieee":     "title",
+	"acm":      "none",
+	"biblatex": "none",
+}
+
+// Author truncation per style: with --shorten all, author lists with more
+// than max names are cut down to the first keep names and "et al."
+
+var truncation = map[string]struct{ max, keep int }{
+	"ieee":     {max: 6, keep: 1},
+	"acm":      {max: 2, keep: 1},
+	"biblatex": {max: 2, keep: 1},
+}
+
+// Junk fields per style: fields that reference managers export but that
+// have no place in a bibliography. See the --junk-policy flag for what
+// happens to them.
+
+var junkFields = map[string][]string{
+	"ieee":     append([]string{"keywords", "annotation"}, commonJunkFields...),
+	"acm":      append([]string{"keywords", "annotation"}, commonJunkFields...),
+	"biblatex": commonJunkFields,
+}
+
+var commonJunkFields = []string{
+	"abstract",
+	"annote",
+	"bdsk-file-1",
+	"bdsk-url-1",
+	"bibsource",
+	"biburl",
+	"date-added",
+	"date-modified",
+	"file",
+	"groups",
+	"mendeley-groups",
+	"mendeley-tags",
+	"owner",
+	"priority",
+	"qualityassured",
+	"ranking",
+	"readstatus",
+	"timestamp",
+}
+
 // Entry types
 
 var fields = map[string]map[string][]string{
@@ -53,6 +101,15 @@ var fields = map[string]map[string][]string{
 			"year",
 		},
 
+		"misc": {
+			"author",
+			"title",
+			"howpublished",
+			"month",
+			"year",
+			"note",
+		},
+
 		"online": {
 			"author",
 			"title",
@@ -160,6 +217,20 @@ var fields = map[string]map[string][]string{
 			"year",
 		},
 
+		"misc": {
+			"author",
+			"title",
+			"howpublished",
+			"month",
+			"year",
+			"eprint",
+			"archiveprefix",
+			"primaryclass",
+			"url",
+			"doi",
+			"note",
+		},
+
 		"online": {
 			"author",
 			"organization",
@@ -200,8 +271,9 @@ var fields = map[string]map[string][]string{
 			"journal",
 			"volume",
 			"number",
-			"month",
+			"date",
 			"year",
+			"month",
 			"issn",
 			"pages",
 			"articleno",
@@ -211,16 +283,20 @@ var fields = map[string]map[string][]string{
 			"publisher",
 			"address",
 			"issue_date",
+			"ids",
 		},
 
 		"book": {
 			"author",
 			"title",
+			"date",
 			"year",
+			"month",
 			"isbn",
 			"publisher",
 			"address",
 			"editor",
+			"ids",
 		},
 
 		"incollection": {
@@ -229,7 +305,10 @@ var fields = map[string]map[string][]string{
 			"booktitle",
 			"publisher",
 			"pages",
+			"date",
 			"year",
+			"month",
+			"ids",
 		},
 
 		"inproceedings": {
@@ -237,8 +316,9 @@ var fields = map[string]map[string][]string{
 			"title",
 			"booktitle",
 			"pages",
-			"month",
+			"date",
 			"year",
+			"month",
 			"publisher",
 			"address",
 			"series",
@@ -247,6 +327,7 @@ var fields = map[string]map[string][]string{
 			"url",
 			"doi",
 			"pubstate",
+			"ids",
 		},
 
 		"mastersthesis": {
@@ -254,8 +335,26 @@ var fields = map[string]map[string][]string{
 			"title",
 			"institution",
 			"address",
+			"date",
+			"year",
 			"month",
+			"ids",
+		},
+
+		"misc": {
+			"author",
+			"title",
+			"howpublished",
+			"date",
 			"year",
+			"month",
+			"eprint",
+			"archiveprefix",
+			"primaryclass",
+			"url",
+			"doi",
+			"note",
+			"ids",
 		},
 
 		"online": {
@@ -263,20 +362,24 @@ var fields = map[string]map[string][]string{
 			"organization",
 			"title",
 			"url",
-			"month",
+			"date",
 			"year",
+			"month",
 			"note",
 			"urldate",
+			"ids",
 		},
 
 		"patent": {
 			"author",
 			"title",
 			"number",
+			"date",
 			"year",
 			"month",
 			"holder",
 			"type",
+			"ids",
 		},
 
 		"phdthesis": {
@@ -285,8 +388,10 @@ var fields = map[string]map[string][]string{
 			"advisor",
 			"institution",
 			"address",
-			"month",
+			"date",
 			"year",
+			"month",
+			"ids",
 		},
 
 		"techreport": {
@@ -296,17 +401,62 @@ var fields = map[string]map[string][]string{
 			"address",
 			"url",
 			"number",
-			"month",
+			"date",
 			"year",
+			"month",
+			"ids",
 		},
 
 		"unpublished": {
 			"author",
 			"title",
-			"month",
+			"date",
 			"year",
+			"month",
 			"eprint",
 			"pubstate",
+			"ids",
 		},
 	},
 }
+
+// Mandatory fields per style and entry type: an entry without one of these
+// is incomplete, see the --missing flag. Alternatives are separated by "|".
+
+var mandatory = map[string]map[string][]string{
+	"ieee": {
+		"article":       {"author", "title", "journal", "year"},
+		"book":          {"author|editor", "title", "publisher", "year"},
+		"incollection":  {"author", "title", "booktitle", "publisher", "year"},
+		"inproceedings": {"author", "title", "booktitle", "year"},
+		"mastersthesis": {"author", "title", "school", "year"},
+		"online":        {"title", "url"},
+		"phdthesis":     {"author", "title", "school", "year"},
+		"techreport":    {"author", "title", "institution", "year"},
+		"unpublished":   {"author", "title", "note"},
+	},
+
+	"acm": {
+		"article":       {"author", "title", "journal", "year"},
+		"book":          {"author|editor", "title", "publisher", "year"},
+		"incollection":  {"author", "title", "booktitle", "publisher", "year"},
+		"inproceedings": {"author", "title", "booktitle", "year"},
+		"mastersthesis": {"author", "title", "school", "year"},
+		"online":        {"title", "url"},
+		"phdthesis":     {"author", "title", "school", "year"},
+		"techreport":    {"author", "title", "institution", "year"},
+	},
+
+	"biblatex": {
+		"article":       {"author", "title", "journal|journaltitle", "date|year"},
+		"book":          {"author|editor", "title", "date|year"},
+		"incollection":  {"author", "title", "booktitle", "date|year"},
+		"inproceedings": {"author", "title", "booktitle", "date|year"},
+		"mastersthesis": {"author", "title", "institution|school", "date|year"},
+		"online":        {"author|editor|organization", "title", "url", "date|year|urldate"},
+		"patent":        {"author", "title", "number", "date|year"},
+		"phdthesis":     {"author", "title", "institution|school", "date|year"},
+		"techreport":    {"author", "title", "institution", "date|year"},
+		"unpublished":   {"author", "title", "date|year"},
+	},
+}
@@ -3,3 +3,5 @@ module github.com/pfandzelter/bibclean
 go 1.22
 
 require github.com/caltechlibrary/bibtex v0.0.8
+
+require gopkg.in/yaml.v3 v3.0.1
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pfandzelter/bibclean/pkg/bibtex"
+)
+
+// reportMissing prints the fields that each element is missing and how
+// often each field is missing overall. It returns the number of incomplete
+// elements.
+func reportMissing(elements []*bibtex.Element) int {
+	counts := make(map[string]int)
+	var incomplete []*bibtex.Element
+
+	for _, element := range elements {
+		if len(element.Missing) == 0 {
+			continue
+		}
+
+		incomplete = append(incomplete, element)
+		for _, field := range element.Missing {
+			counts[field]++
+		}
+	}
+
+	if len(incomplete) == 0 {
+		return 0
+	}
+
+	slices.SortFunc(incomplete, func(a, b *bibtex.Element) int {
+		return cmp.Compare(strings.ToLower(a.ID), strings.ToLower(b.ID))
+	})
+
+	fmt.Printf("%d of %d entries are missing required fields:\n", len(incomplete), len(elements))
+
+	for _, element := range incomplete {
+		fmt.Printf("  %s (@%s): %s\n", element.ID, element.Type, strings.Join(element.Missing, ", "))
+	}
+
+	fields := make([]string, 0, len(counts))
+	for field := range counts {
+		fields = append(fields, field)
+	}
+
+	// most often missing first
+	slices.SortFunc(fields, func(a, b string) int {
+		if c := cmp.Compare(counts[b], counts[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+
+	fmt.Printf("missing fields:\n")
+
+	for _, field := range fields {
+		fmt.Printf("  %s: %d\n", field, counts[field])
+	}
+
+	return len(incomplete)
+}
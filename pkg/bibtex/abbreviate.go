@@ -0,0 +1,277 @@
+package bibtex
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Kinds of tokens that titles are split into for abbreviating.
+const (
+	tokenWord      = iota // letters and digits
+	tokenSpace            // whitespace and ties
+	tokenPunct            // a single punctuation character
+	tokenProtected        // a brace group, a command, or math, never changed
+)
+
+type token struct {
+	kind int
+	text string
+}
+
+// tokenize splits a title into words, whitespace, punctuation, and
+// protected parts. Words end at anything that is not a letter or digit, so
+// matching whole tokens respects word boundaries.
+func tokenize(s string) []token {
+	var tokens []token
+
+	r := []rune(s)
+	for i := 0; i < len(r); {
+		start := i
+
+		switch c := r[i]; {
+		case c == '{' || c == '$':
+			// up to the matching brace or dollar sign
+			depth := 0
+			for ; i < len(r); i++ {
+				if c == '$' && i > start && r[i] == '$' {
+					break
+				}
+				if r[i] == '{' {
+					depth++
+				} else if r[i] == '}' {
+					depth--
+					if c == '{' && depth == 0 {
+						break
+					}
+				}
+			}
+			i = min(i+1, len(r))
+			tokens = append(tokens, token{tokenProtected, string(r[start:i])})
+		case c == '\\':
+			i++
+			for i < len(r) && unicode.IsLetter(r[i]) {
+				i++
+			}
+			if i == start+1 && i < len(r) {
+				i++
+			}
+			tokens = append(tokens, token{tokenProtected, string(r[start:i])})
+		case unicode.IsLetter(c) || unicode.IsDigit(c):
+			for i < len(r) && (unicode.IsLetter(r[i]) || unicode.IsDigit(r[i])) {
+				i++
+			}
+			tokens = append(tokens, token{tokenWord, string(r[start:i])})
+		case unicode.IsSpace(c) || c == '~':
+			for i < len(r) && (unicode.IsSpace(r[i]) || r[i] == '~') {
+				i++
+			}
+			tokens = append(tokens, token{tokenSpace, string(r[start:i])})
+		default:
+			i++
+			tokens = append(tokens, token{tokenPunct, string(r[start:i])})
+		}
+	}
+
+	return tokens
+}
+
+// trieNode is a node in a trie of lowercase tokens. forms is set if a
+// pattern ends at the node.
+type trieNode struct {
+	next  map[string]*trieNode
+	forms []string
+}
+
+// abbreviations is a compiled set of patterns (words or phrases such as
+// "Technical Digest") and the forms to replace them with. Patterns are
+// matched case-insensitively on whole words, the longest match wins.
+type abbreviations struct {
+	root *trieNode
+}
+
+// compileAbbreviations builds the matcher for patterns and their forms.
+// Patterns are compared by their words and punctuation, so whitespace does
+// not matter.
+func compileAbbreviations(patterns map[string][]string) *abbreviations {
+	a := &abbreviations{root: &trieNode{next: make(map[string]*trieNode)}}
+
+	for pattern, forms := range patterns {
+		node := a.root
+		for _, t := range tokenize(pattern) {
+			if t.kind == tokenSpace {
+				continue
+			}
+
+			key := strings.ToLower(t.text)
+			if node.next[key] == nil {
+				node.next[key] = &trieNode{next: make(map[string]*trieNode)}
+			}
+			node = node.next[key]
+		}
+
+		if node == a.root {
+			continue
+		}
+
+		for _, f := range forms {
+			if !containsFold(node.forms, f) {
+				node.forms = append(node.forms, f)
+			}
+		}
+
+		// the order of the patterns must not decide which form comes first
+		sort.Strings(node.forms)
+	}
+
+	return a
+}
+
+// abbreviationMatch is a pattern found in a title.
+type abbreviationMatch struct {
+	// text is the matched text as it is in the title.
+	text string
+	// forms are what the pattern can be replaced with.
+	forms []string
+	// followed is set if the match is followed by a word (other than a
+	// preposition or conjunction) without punctuation in between.
+	followed bool
+}
+
+// replace finds the longest matches in s, from left to right, and replaces
+// them with what pick returns. If pick returns false, the match is kept.
+// An empty replacement removes the match, but only between two words.
+// Replacements are written in the case of the match: lowercase, capitalized,
+// or all caps.
+func (a *abbreviations) replace(s string, pick func(m abbreviationMatch) (string, bool)) string {
+	tokens := tokenize(s)
+
+	var b strings.Builder
+
+	for i := 0; i < len(tokens); i++ {
+		end := -1
+		var forms []string
+
+		if tokens[i].kind == tokenWord {
+			node := a.root
+			for j := i; j < len(tokens); j++ {
+				t := tokens[j]
+				if t.kind == tokenSpace && j > i {
+					continue
+				}
+				if t.kind != tokenWord && t.kind != tokenPunct {
+					break
+				}
+
+				node = node.next[strings.ToLower(t.text)]
+				if node == nil {
+					break
+				}
+
+				if node.forms != nil {
+					end, forms = j, node.forms
+				}
+			}
+		}
+
+		if end < 0 {
+			b.WriteString(tokens[i].text)
+			continue
+		}
+
+		var text strings.Builder
+		for _, t := range tokens[i : end+1] {
+			text.WriteString(t.text)
+		}
+
+		m := abbreviationMatch{
+			text:     text.String(),
+			forms:    forms,
+			followed: followedByWord(tokens[end+1:]),
+		}
+
+		form, ok := pick(m)
+
+		betweenWords := i > 0 && tokens[i-1].kind == tokenSpace && end+2 < len(tokens) && tokens[end+1].kind == tokenSpace
+		if !ok || (form == "" && !betweenWords) {
+			b.WriteString(m.text)
+			i = end
+			continue
+		}
+
+		if form == "" {
+			// drop the space after the removed word, too
+			i = end + 1
+			continue
+		}
+
+		b.WriteString(matchCase(form, tokens[i].text))
+		i = end
+
+		// "Proceedings." becomes "Proc." and not "Proc.."
+		if strings.HasSuffix(form, ".") && i+1 < len(tokens) && tokens[i+1].text == "." {
+			i++
+		}
+	}
+
+	return b.String()
+}
+
+// followedByWord reports whether the tokens after a match start with a
+// word that is not a preposition or conjunction, with nothing but
+// whitespace in between.
+func followedByWord(rest []token) bool {
+	for _, t := range rest {
+		switch t.kind {
+		case tokenSpace:
+			continue
+		case tokenWord:
+			_, omitted := iso4Omitted[strings.ToLower(t.text)]
+			return !omitted
+		default:
+			return t.kind == tokenProtected
+		}
+	}
+
+	return false
+}
+
+// matchCase writes a form in the case of the word it replaces: lowercase,
+// capitalized, or all caps.
+func matchCase(form string, like string) string {
+	switch {
+	case isAllCaps(like):
+		return strings.ToUpper(form)
+	case strings.IndexFunc(like, unicode.IsUpper) == 0:
+		r := []rune(form)
+		r[0] = unicode.ToUpper(r[0])
+		return string(r)
+	default:
+		return strings.ToLower(form)
+	}
+}
+
+// shortforms compiles the IEEE tables for shortening, each long form has a
+// single short form.
+func shortforms(table *map[string]string) *abbreviations {
+	patterns := make(map[string][]string)
+	for long, short := range *table {
+		patterns[long] = []string{strings.TrimSpace(short)}
+	}
+
+	return compileAbbreviations(patterns)
+}
+
+var (
+	titleAbbreviations = shortforms(ieeeTitleShortforms)
+	wordAbbreviations  = shortforms(ieeeShortforms)
+)
+
+// shorten replaces the long forms in a value with their short forms.
+func (a *abbreviations) shorten(val string) string {
+	return mapStrings(val, func(s string) string {
+		return a.replace(s, func(m abbreviationMatch) (string, bool) {
+			return m.forms[0], true
+		})
+	})
+}
@@ -0,0 +1,96 @@
+package bibtex
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestAbbreviationsShorten(t *testing.T) {
+	a := compileAbbreviations(map[string][]string{
+		"Technical":           {"Tech."},
+		" Technical  Digest ": {"Tech. Dig."},
+		"Proceedings":         {"Proc."},
+		"Computer":            {"Comput."},
+		"Systems":             {"Syst."},
+		"of":                  {""},
+		"on":                  {""},
+	})
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		// the longest match wins, whitespace in the pattern does not matter
+		{`"Technical Digest"`, `"Tech. Dig."`},
+		{`"Technical~Digest"`, `"Tech. Dig."`},
+		{`"Technical Report"`, `"Tech. Report"`},
+		{`"Technical"`, `"Tech."`},
+
+		// only whole words
+		{`"Computerized Systems"`, `"Computerized Syst."`},
+		{`"Supercomputer"`, `"Supercomputer"`},
+		{`"Computer-Aided Design"`, `"Comput.-Aided Design"`},
+		{`"Computer/Systems"`, `"Comput./Syst."`},
+
+		// the case of the replaced word
+		{`"computer systems"`, `"comput. syst."`},
+		{`"COMPUTER SYSTEMS"`, `"COMPUT. SYST."`},
+		{`"CoMPuter"`, `"Comput."`},
+
+		// protected text is left alone
+		{`"{Computer} Systems"`, `"{Computer} Syst."`},
+		{`"$Computer$ Systems"`, `"$Computer$ Syst."`},
+		{`"\Computer Systems"`, `"\Computer Syst."`},
+
+		// no double periods
+		{`"Proceedings. Computer"`, `"Proc. Comput."`},
+
+		// removed words only between two words
+		{`"Proceedings of the Computer Systems"`, `"Proc. the Comput. Syst."`},
+		{`"Transactions on Computer Systems"`, `"Transactions Comput. Syst."`},
+		{`"Of Computer"`, `"Of Comput."`},
+		{`"Computer of"`, `"Comput. of"`},
+		{`"Computer, of Systems"`, `"Comput., Syst."`},
+		{`"Computer (of) Systems"`, `"Comput. (of) Syst."`},
+
+		{`jun # " Computer"`, `jun # " Comput."`},
+	}
+
+	for _, tt := range tests {
+		if got := a.shorten(tt.in); got != tt.want {
+			t.Errorf("shorten(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAbbreviationsReplace(t *testing.T) {
+	a := compileAbbreviations(map[string][]string{
+		"Appl.":  {"Applied", "Applications"},
+		"Phys.":  {"Physics"},
+		"Comput": {"Computing"},
+	})
+
+	var matches []abbreviationMatch
+	got := a.replace("Appl. Phys. and Comput. Appl. in {Appl.} Appl.", func(m abbreviationMatch) (string, bool) {
+		matches = append(matches, m)
+		return "", false
+	})
+
+	if want := "Appl. Phys. and Comput. Appl. in {Appl.} Appl."; got != want {
+		t.Errorf("replace() = %q, want %q", got, want)
+	}
+
+	want := []abbreviationMatch{
+		{text: "Appl.", forms: []string{"Applications", "Applied"}, followed: true},
+		{text: "Phys.", forms: []string{"Physics"}, followed: false},
+		{text: "Comput", forms: []string{"Computing"}, followed: false},
+		{text: "Appl.", forms: []string{"Applications", "Applied"}, followed: false},
+		{text: "Appl.", forms: []string{"Applications", "Applied"}, followed: false},
+	}
+
+	if !slices.EqualFunc(matches, want, func(a, b abbreviationMatch) bool {
+		return a.text == b.text && slices.Equal(a.forms, b.forms) && a.followed == b.followed
+	}) {
+		t.Errorf("replace() matched %+v, want %+v", matches, want)
+	}
+}
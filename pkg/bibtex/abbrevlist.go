@@ -0,0 +1,140 @@
+package bibtex
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// AbbreviationList is a journal abbreviation list, e.g., one that a venue
+// publishes for its authors.
+type AbbreviationList struct {
+	// abbreviations maps normalized full venue names (see normalizeVenue)
+	// to their abbreviations.
+	abbreviations map[string]string
+	// expansions maps normalized abbreviations to the full venue names.
+	expansions map[string]string
+}
+
+// AbbreviationLists are abbreviation lists. Earlier lists take precedence.
+type AbbreviationLists []*AbbreviationList
+
+// LoadJabRef reads a journal abbreviation list in JabRef's CSV format, with
+// one "full name;abbreviation" pair per line (further columns are ignored).
+// Earlier lines take precedence.
+func LoadJabRef(data []byte) (*AbbreviationList, error) {
+	l := &AbbreviationList{
+		abbreviations: make(map[string]string),
+		expansions:    make(map[string]string),
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = ';'
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read abbreviation list: %w", err)
+		}
+
+		if len(record) < 2 {
+			line, _ := r.FieldPos(0)
+			return nil, fmt.Errorf("could not read abbreviation list: line %d has no abbreviation", line)
+		}
+
+		full, abbrev := strings.TrimSpace(record[0]), strings.TrimSpace(record[1])
+		if full == "" || abbrev == "" {
+			continue
+		}
+
+		if _, ok := l.abbreviations[normalizeVenue(full)]; !ok {
+			l.abbreviations[normalizeVenue(full)] = abbrev
+		}
+
+		if _, ok := l.expansions[normalizeVenue(abbrev)]; !ok {
+			l.expansions[normalizeVenue(abbrev)] = full
+		}
+	}
+
+	return l, nil
+}
+
+// Abbreviate finds the abbreviation of a venue. The whole venue has to
+// match.
+func (lists AbbreviationLists) Abbreviate(venue string) (string, bool) {
+	for _, l := range lists {
+		if abbrev, ok := l.abbreviations[normalizeVenue(venue)]; ok {
+			return abbrev, true
+		}
+	}
+
+	return "", false
+}
+
+// Expand finds the full name of an abbreviated venue. The whole venue has
+// to match.
+func (lists AbbreviationLists) Expand(abbrev string) (string, bool) {
+	for _, l := range lists {
+		if full, ok := l.expansions[normalizeVenue(abbrev)]; ok {
+			return full, true
+		}
+	}
+
+	return "", false
+}
+
+// venueAbbreviation finds the abbreviation of a venue from the venue
+// database, as written in the entry or under its full name.
+func (lists AbbreviationLists) venueAbbreviation(s string, v *Venue) (string, bool) {
+	if abbrev, ok := lists.Abbreviate(s); ok {
+		return abbrev, true
+	}
+
+	return lists.Abbreviate(v.Name)
+}
+
+// abbreviateFromList replaces a venue that is in one of the lists with its
+// abbreviation.
+func (lists AbbreviationLists) abbreviateFromList(e Element, key string) bool {
+	s, ok := unquote(e.Tags[key])
+	if !ok {
+		return false
+	}
+
+	abbrev, ok := lists.Abbreviate(s)
+	if !ok {
+		return false
+	}
+
+	e.Tags[key] = quote(escapeSpecial(e, key, abbrev))
+
+	return true
+}
+
+// expandFromList replaces an abbreviated venue that is in one of the lists
+// with its full name.
+func (lists AbbreviationLists) expandFromList(e Element, key string) bool {
+	s, ok := unquote(e.Tags[key])
+	if !ok {
+		return false
+	}
+
+	full, ok := lists.Expand(s)
+	if !ok {
+		return false
+	}
+
+	e.Tags[key] = quote(escapeSpecial(e, key, full))
+
+	return true
+}
@@ -0,0 +1,176 @@
+package bibtex
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// new-style (2101.01234) and old-style (cs/0112017, math.GT/0309136)
+	// identifiers, optionally with a version and primary class
+	arXivID    = regexp.MustCompile(`(?i)(\d{4}\.\d{4,5}|[a-z-]+(?:\.[a-z]{2})?/\d{7})(?:v\d+)?(?:\s*\[([a-z-]+(?:\.[a-z]{2})?)\])?`)
+	arXivURL   = regexp.MustCompile(`(?i)arxiv\.org/(?:abs|pdf)/`)
+	arXivDOI   = regexp.MustCompile(`(?i)^10\.48550/arxiv\.`)
+	arXivClass = regexp.MustCompile(`(?i)^[a-z-]+(?:\.[a-z]{2})?$`)
+	// DBLP exports arXiv preprints as journal = {CoRR}, volume = {abs/<id>}
+	arXivCoRR   = regexp.MustCompile(`(?i)^\s*corr\s*$`)
+	arXivVolume = regexp.MustCompile(`(?i)^abs/`)
+)
+
+// arXivFields are fields that only say that an entry is an arXiv preprint
+// when they mention arXiv. They are removed once we know the identifier.
+var arXivFields = []string{"journal", "booktitle", "howpublished", "note", "publisher"}
+
+// isArXivVenue reports whether a field value names arXiv, including DBLP's
+// "CoRR".
+func isArXivVenue(val string) bool {
+	s, ok := unquote(val)
+	if !ok {
+		s = val
+	}
+
+	return strings.Contains(strings.ToLower(s), "arxiv") || arXivCoRR.MatchString(s)
+}
+
+// NormalizeArXiv finds arXiv identifiers anywhere in an entry (eprint,
+// journal, howpublished, note, url, or doi, and DBLP's journal = {CoRR}
+// with volume = {abs/<id>}) and rewrites preprints to the canonical form of
+// the style: @misc with eprint, archivePrefix, and primaryClass for biblatex
+// and ACM, and @misc with an "arXiv:<id>" note (after any other note) for
+// IEEEtran. Versions are stripped from identifiers. Entries that have been
+// published elsewhere keep their type and only get their eprint cleaned.
+func NormalizeArXiv(style string) func(e Element) Element {
+	return func(e Element) Element {
+		id, class, preprint := findArXiv(e)
+		if id == "" {
+			return e
+		}
+
+		if !preprint {
+			if _, ok := e.Tags["eprint"]; ok {
+				e.Tags["eprint"] = quote(id)
+			}
+			return e
+		}
+
+		for _, f := range arXivFields {
+			if s, ok := e.Tags[f]; ok && isArXivVenue(s) {
+				delete(e.Tags, f)
+			}
+		}
+
+		if v, ok := unquote(e.Tags["volume"]); ok && arXivVolume.MatchString(v) {
+			delete(e.Tags, "volume")
+		}
+
+		delete(e.Tags, "eprint")
+		delete(e.Tags, "eprinttype")
+		delete(e.Tags, "archiveprefix")
+		delete(e.Tags, "eprintclass")
+		delete(e.Tags, "primaryclass")
+
+		e.Type = "misc"
+
+		if style == StyleIEEE {
+			note := "arXiv:" + id
+			if class != "" {
+				note += " [" + class + "]"
+			}
+
+			// a note that is not about arXiv stays
+			if n, ok := unquote(e.Tags["note"]); ok && strings.TrimSpace(n) != "" {
+				note = strings.TrimRight(strings.TrimSpace(n), " ,;.") + ", " + note
+			}
+
+			e.Tags["note"] = quote(note)
+
+			return e
+		}
+
+		e.Tags["eprint"] = quote(id)
+		e.Tags["archiveprefix"] = quote("arXiv")
+		if class != "" {
+			e.Tags["primaryclass"] = quote(class)
+		}
+
+		return e
+	}
+}
+
+// findArXiv looks for an arXiv identifier and primary class in an entry and
+// also reports whether the entry is just a preprint, i.e., whether it has no
+// venue other than arXiv.
+func findArXiv(e Element) (id string, class string, preprint bool) {
+	if c, ok := e.Tags["primaryclass"]; ok {
+		class, _ = unquote(c)
+	} else if c, ok := e.Tags["eprintclass"]; ok {
+		class, _ = unquote(c)
+	}
+
+	match := func(s string) bool {
+		m := arXivID.FindStringSubmatch(s)
+		if m == nil {
+			return false
+		}
+
+		id = m[1]
+		if class == "" && m[2] != "" {
+			class = m[2]
+		}
+
+		return true
+	}
+
+	// an eprint that is explicitly (or most likely) from arXiv
+	if eprint, ok := e.Tags["eprint"]; ok {
+		prefix := strings.ToLower(e.Tags["archiveprefix"] + e.Tags["eprinttype"])
+		if prefix == "" || strings.Contains(prefix, "arxiv") {
+			match(eprint)
+		}
+	}
+
+	// "arXiv preprint arXiv:2101.01234" and similar
+	for _, f := range arXivFields {
+		if s, ok := e.Tags[f]; ok && isArXivVenue(s) {
+			if id == "" {
+				match(s)
+			}
+			preprint = true
+		}
+	}
+
+	if v, ok := unquote(e.Tags["volume"]); ok && id == "" && arXivVolume.MatchString(v) {
+		match(v)
+	}
+
+	if u, ok := e.Tags["url"]; ok && id == "" && arXivURL.MatchString(u) {
+		match(arXivURL.Split(u, 2)[1])
+	}
+
+	if d, ok := e.Tags["doi"]; ok && id == "" {
+		if s, _ := unquote(d); arXivDOI.MatchString(s) {
+			match(arXivDOI.ReplaceAllString(s, ""))
+		}
+	}
+
+	if id == "" {
+		return "", "", false
+	}
+
+	if !arXivClass.MatchString(class) {
+		class = ""
+	}
+
+	// an entry that has a venue other than arXiv has been published
+	for _, f := range []string{"journal", "booktitle"} {
+		if s, ok := e.Tags[f]; ok && !isArXivVenue(s) {
+			return id, class, false
+		}
+	}
+
+	if !preprint {
+		preprint = e.Type == "article" || e.Type == "misc" || e.Type == "unpublished" || e.Type == "online"
+	}
+
+	return id, class, preprint
+}
@@ -0,0 +1,62 @@
+package bibtex
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestNormalizeArXiv(t *testing.T) {
+	tests := []struct {
+		name  string
+		style string
+		in    map[string]string
+		typ   string
+		want  map[string]string
+	}{
+		{
+			name:  "preprint for IEEE",
+			style: StyleIEEE,
+			in:    map[string]string{"journal": `"arXiv preprint arXiv:2101.01234v2"`},
+			typ:   "misc",
+			want:  map[string]string{"note": `"arXiv:2101.01234"`},
+		},
+		{
+			name:  "other note stays for IEEE",
+			style: StyleIEEE,
+			in:    map[string]string{"eprint": `"2101.01234"`, "archiveprefix": `"arXiv"`, "primaryclass": `"cs.DC"`, "note": `"Presented at the workshop."`},
+			typ:   "misc",
+			want:  map[string]string{"note": `"Presented at the workshop, arXiv:2101.01234 [cs.DC]"`},
+		},
+		{
+			name:  "note about arXiv is replaced for IEEE",
+			style: StyleIEEE,
+			in:    map[string]string{"note": `"arXiv: cs/0112017"`},
+			typ:   "misc",
+			want:  map[string]string{"note": `"arXiv:cs/0112017"`},
+		},
+		{
+			name:  "DBLP CoRR for biblatex",
+			style: StyleBibLaTeX,
+			in:    map[string]string{"journal": `"CoRR"`, "volume": `"abs/2101.01234"`, "note": `"Presented at the workshop"`},
+			typ:   "misc",
+			want:  map[string]string{"eprint": `"2101.01234"`, "archiveprefix": `"arXiv"`, "note": `"Presented at the workshop"`},
+		},
+		{
+			name:  "published elsewhere",
+			style: StyleIEEE,
+			in:    map[string]string{"journal": `"Nature"`, "eprint": `"2101.01234v3"`},
+			typ:   "article",
+			want:  map[string]string{"journal": `"Nature"`, "eprint": `"2101.01234"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NormalizeArXiv(tt.style)(Element{ID: "test", Type: "article", Tags: tt.in})
+
+			if e.Type != tt.typ || !maps.Equal(e.Tags, tt.want) {
+				t.Errorf("got @%s %v, want @%s %v", e.Type, e.Tags, tt.typ, tt.want)
+			}
+		})
+	}
+}
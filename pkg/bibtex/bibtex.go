@@ -22,6 +22,8 @@ import (
 	"encoding/xml"
 	"fmt"
 	"regexp"
+	"slices"
+	"sort"
 	"strings"
 
 	// Caltech Library packages
@@ -68,6 +70,18 @@ THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS" AND
 
 const MISSING_VAL = "MISSING"
 
+// Policies for fields that an element is missing, see Element.Format
+const (
+	// MissingPlaceholder writes "field = MISSING" into the element
+	MissingPlaceholder = "placeholder"
+	// MissingComment lists the missing fields in a comment above the element
+	MissingComment = "comment"
+	// MissingOmit leaves missing fields out
+	MissingOmit = "omit"
+	// MissingError leaves missing fields out, the caller should fail
+	MissingError = "error"
+)
+
 // Generic Element
 type Element struct {
 	XMLName      xml.Name          `json:"-"`
@@ -76,6 +90,9 @@ type Element struct {
 	Type         string            `xml:"type" json:"type"`
 	Tags         map[string]string `xml:"tags" json:"tags"`
 	RequiredKeys *TagTypes
+	// Missing are the fields the element should have but does not,
+	// alternatives are separated by "|", e.g., "author|editor"
+	Missing []string `xml:"-" json:"-"`
 }
 
 type Elements []*Element
@@ -84,8 +101,15 @@ type TagTypes struct {
 	Required []string
 }
 
-// String renders a single BibTeX element
+// String renders a single BibTeX element, with missing fields as a comment
 func (element *Element) String() string {
+	return element.Format(MissingComment)
+}
+
+// Format renders a single BibTeX element. Fields that the element should
+// have but does not (see CheckRequired) are rendered according to the
+// missing policy.
+func (element *Element) Format(missing string) string {
 	var out []string
 
 	keys := element.RequiredKeys.Required
@@ -107,6 +131,16 @@ func (element *Element) String() string {
 		}
 	}
 
+	// for alternatives such as "author|editor", the first one is a placeholder
+	placeholders := make([]string, 0, len(element.Missing))
+	for _, m := range element.Missing {
+		placeholders = append(placeholders, strings.Split(m, "|")[0])
+	}
+
+	if missing == MissingComment && len(element.Missing) > 0 {
+		out = append(out, fmt.Sprintf("%% missing: %s", strings.Join(element.Missing, ", ")))
+	}
+
 	if len(element.ID) > 0 {
 		out = append(out, fmt.Sprintf("@%s{%s,", element.Type, element.ID))
 	} else {
@@ -118,11 +152,19 @@ func (element *Element) String() string {
 		if len(val) != 0 {
 			val := regexp.MustCompile(`\s+`).ReplaceAllString(val, " ")
 			out = append(out, fmt.Sprintf("    %s = %s,", ky, val))
-		} else {
+		} else if missing == MissingPlaceholder && slices.Contains(placeholders, ky) {
 			out = append(out, fmt.Sprintf("    %s = %s,", ky, MISSING_VAL))
 		}
 	}
 
+	if missing == MissingPlaceholder {
+		for _, ky := range placeholders {
+			if _, ok := neededKeys[ky]; !ok {
+				out = append(out, fmt.Sprintf("    %s = %s,", ky, MISSING_VAL))
+			}
+		}
+	}
+
 	// remove trailing comma
 	if last := len(out) - 1; last >= 0 {
 		if char := len(out[last]) - 1; char >= 0 && out[last][char] == ',' {
@@ -134,6 +176,40 @@ func (element *Element) String() string {
 	return strings.Join(out, "\n")
 }
 
+// Extract removes the given fields from the element and returns them as an
+// element of their own with the same ID and type, e.g., to write them to a
+// separate file. If the element has none of the fields, Extract returns nil.
+func (element *Element) Extract(fields map[string]struct{}) *Element {
+	var keys []string
+
+	for key := range element.Tags {
+		if _, ok := fields[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	sort.Strings(keys)
+
+	extracted := &Element{
+		ID:           element.ID,
+		Key:          element.Key,
+		Type:         element.Type,
+		Tags:         make(map[string]string, len(keys)),
+		RequiredKeys: &TagTypes{Required: keys},
+	}
+
+	for _, key := range keys {
+		extracted.Tags[key] = element.Tags[key]
+		delete(element.Tags, key)
+	}
+
+	return extracted
+}
+
 //
 // Parser related structures
 //
@@ -181,6 +257,22 @@ func Bib(token *tok.Token, buf []byte) (*tok.Token, []byte) {
 	return token, buf
 }
 
+// requiredKeys combines the default fields of an element type with the
+// additional fields the user asked for.
+func requiredKeys(defaultElements *TagTypes, additionalFields map[string]struct{}) *TagTypes {
+	r := &TagTypes{
+		Required: make([]string, len(defaultElements.Required), len(defaultElements.Required)+len(additionalFields)),
+	}
+
+	copy(r.Required, defaultElements.Required)
+
+	for f := range additionalFields {
+		r.Required = append(r.Required, f)
+	}
+
+	return r
+}
+
 func mkElement(elementType string, defaultElements *TagTypes, additionalFields map[string]struct{}, buf []byte) (*Element, error) {
 	var (
 		key     []byte
@@ -194,16 +286,7 @@ func mkElement(elementType string, defaultElements *TagTypes, additionalFields m
 
 	element := new(Element)
 	element.Type = elementType
-
-	element.RequiredKeys = &TagTypes{
-		Required: make([]string, len(defaultElements.Required), len(defaultElements.Required)+len(additionalFields)),
-	}
-
-	copy(element.RequiredKeys.Required, defaultElements.Required)
-
-	for f := range additionalFields {
-		element.RequiredKeys.Required = append(element.RequiredKeys.Required, f)
-	}
+	element.RequiredKeys = requiredKeys(defaultElements, additionalFields)
 
 	tags = make(map[string]string)
 
@@ -279,6 +362,7 @@ func Parse(buf []byte, defaultElements *map[string][]string, additionalFields ma
 		skipped     []byte
 		entrySource []byte
 		LF          = []byte("\n")
+		unknown     = make(map[*Element]int)
 	)
 
 	// convert the default elements map to a map of TagTypes
@@ -311,14 +395,20 @@ func Parse(buf []byte, defaultElements *map[string][]string, additionalFields ma
 					// OK, we have an entry, let's process it.
 					et := strings.ToLower(string(elementType))
 
-					if _, ok := defaultFields[et]; !ok {
-						return nil, fmt.Errorf("element type %s is unknown (line %d)", et, lineNo)
+					// unknown types may still be fixed by a plugin, we check them later
+					fields, known := defaultFields[et]
+					if !known {
+						fields = &TagTypes{}
 					}
 
-					element, err := mkElement(et, defaultFields[et], additionalFields[et], entrySource)
+					element, err := mkElement(et, fields, additionalFields[et], entrySource)
 					if err != nil {
 						return elements, fmt.Errorf("error parsing element at l. %d, %s", lineNo, err)
 					}
+
+					if !known {
+						unknown[element] = lineNo
+					}
 					lineNo = lineNo + bytes.Count(entrySource, LF)
 					// OK, we have an element, let's append to our array...
 
@@ -334,7 +424,28 @@ func Parse(buf []byte, defaultElements *map[string][]string, additionalFields ma
 	// run plugins
 	for _, plugin := range plugins {
 		for _, element := range elements {
+			t := element.Type
 			*element = plugin(*element)
+
+			// plugins may change the type, which changes the fields we need
+			if element.Type != t {
+				if _, ok := defaultFields[element.Type]; !ok {
+					return nil, fmt.Errorf("element type %s of %s is unknown", element.Type, element.ID)
+				}
+
+				element.RequiredKeys = requiredKeys(defaultFields[element.Type], additionalFields[element.Type])
+			}
+		}
+	}
+
+	for _, element := range elements {
+		line, ok := unknown[element]
+		if !ok {
+			continue
+		}
+
+		if _, ok := defaultFields[element.Type]; !ok {
+			return nil, fmt.Errorf("element type %s is unknown (line %d)", element.Type, line)
 		}
 	}
 
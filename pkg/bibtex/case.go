@@ -0,0 +1,288 @@
+package bibtex
+
+import (
+	"strings"
+	"unicode"
+)
+
+// smallWords are articles, conjunctions, and short prepositions that stay
+// lowercase in title case unless they start or end the title (or follow a
+// colon).
+var smallWords = map[string]struct{}{
+	"a":    {},
+	"an":   {},
+	"and":  {},
+	"as":   {},
+	"at":   {},
+	"but":  {},
+	"by":   {},
+	"for":  {},
+	"from": {},
+	"in":   {},
+	"into": {},
+	"nor":  {},
+	"of":   {},
+	"on":   {},
+	"onto": {},
+	"or":   {},
+	"per":  {},
+	"the":  {},
+	"to":   {},
+	"via":  {},
+	"vs":   {},
+	"with": {},
+}
+
+// TitleCase converts the title to title case (as IEEE wants it): every word
+// is capitalized except for small words in the middle of the title. Words
+// that already contain uppercase letters after their first letter (acronyms
+// such as "IoT") and anything in braces, math, or commands are left alone.
+func TitleCase(e Element) Element {
+	return convertCase(e, func(w caseWord, first, last bool) string {
+		if w.mixed() {
+			return w.s
+		}
+
+		if _, ok := smallWords[w.letters()]; ok && !first && !last {
+			return w.lower()
+		}
+
+		return w.capitalize()
+	})
+}
+
+// SentenceCase converts the title to sentence case (as APA and most biblatex
+// styles want it): only the first word and the first word after a colon are
+// capitalized. As with TitleCase, acronyms and protected text are kept.
+// Proper nouns cannot be told apart from other words, so every capitalized
+// word that is lowercased is reported: protect it with braces if it is a
+// name.
+func SentenceCase(e Element) Element {
+	var lowered []string
+
+	e = convertCase(e, func(w caseWord, first, last bool) string {
+		if w.mixed() {
+			return w.s
+		}
+
+		if first {
+			return w.capitalize()
+		}
+
+		l := w.lower()
+		if l != w.s {
+			lowered = append(lowered, w.s)
+		}
+
+		return l
+	})
+
+	if len(lowered) > 0 {
+		warnf(e, "lowercased %s in title, protect proper nouns with braces", strings.Join(lowered, ", "))
+	}
+
+	return e
+}
+
+// convertCase applies conv to every word (and every part of a hyphenated
+// compound) of the title.
+func convertCase(e Element, conv func(w caseWord, first, last bool) string) Element {
+	val, ok := e.Tags["title"]
+	if !ok {
+		return e
+	}
+
+	s, ok := unquote(val)
+	if !ok {
+		return e
+	}
+
+	tokens := splitWords(s)
+
+	// the last token that is a word, to know which word ends the title
+	lastWord := -1
+	for i, t := range tokens {
+		if strings.TrimSpace(t) != "" {
+			lastWord = i
+		}
+	}
+
+	first := true
+	for i, t := range tokens {
+		if strings.TrimSpace(t) == "" {
+			continue
+		}
+
+		parts := splitCompound(t)
+		for j, p := range parts {
+			if p == "-" {
+				continue
+			}
+
+			parts[j] = conv(newCaseWord(p), first && j == 0, i == lastWord && j == len(parts)-1)
+		}
+		tokens[i] = strings.Join(parts, "")
+
+		// a colon, question mark, or exclamation mark starts a new "sentence"
+		first = strings.ContainsAny(t[len(t)-1:], ":?!")
+	}
+
+	e.Tags["title"] = quote(strings.Join(tokens, ""))
+
+	return e
+}
+
+// splitWords splits s into words and the whitespace between them. Whitespace
+// in braces or math does not separate words.
+func splitWords(s string) []string {
+	var tokens []string
+
+	depth := 0
+	math := false
+	start := 0
+	space := false
+
+	for i, r := range s {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '$':
+			math = !math
+		}
+
+		isSpace := unicode.IsSpace(r) && depth == 0 && !math
+		if isSpace != space && i > start {
+			tokens = append(tokens, s[start:i])
+			start = i
+		}
+		space = isSpace
+	}
+
+	if start < len(s) {
+		tokens = append(tokens, s[start:])
+	}
+
+	return tokens
+}
+
+// splitCompound splits a word at hyphens outside of braces and math. The
+// hyphens are kept as separate parts.
+func splitCompound(w string) []string {
+	var parts []string
+
+	depth := 0
+	math := false
+	start := 0
+
+	for i, r := range w {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '$':
+			math = !math
+		case '-':
+			if depth != 0 || math {
+				continue
+			}
+			if i > start {
+				parts = append(parts, w[start:i])
+			}
+			parts = append(parts, "-")
+			start = i + 1
+		}
+	}
+
+	if start < len(w) {
+		parts = append(parts, w[start:])
+	}
+
+	return parts
+}
+
+// caseWord is a word together with the positions of the letters that may be
+// changed, i.e., that are not in braces, math, or a command name.
+type caseWord struct {
+	s     string
+	r     []rune
+	plain []int
+}
+
+func newCaseWord(s string) caseWord {
+	w := caseWord{s: s, r: []rune(s)}
+
+	depth := 0
+	math := false
+	command := false
+
+	for i, r := range w.r {
+		switch {
+		case r == '{':
+			depth++
+		case r == '}':
+			depth--
+		case r == '$':
+			math = !math
+		case r == '\\':
+			command = true
+			continue
+		}
+
+		if command && unicode.IsLetter(r) {
+			continue
+		}
+		command = false
+
+		if depth == 0 && !math && unicode.IsLetter(r) {
+			w.plain = append(w.plain, i)
+		}
+	}
+
+	return w
+}
+
+// mixed reports whether the word has an uppercase letter that is not its
+// first letter, in which case we should not touch it.
+func (w caseWord) mixed() bool {
+	for _, i := range w.plain[min(1, len(w.plain)):] {
+		if unicode.IsUpper(w.r[i]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// letters returns the changeable letters of the word in lowercase.
+func (w caseWord) letters() string {
+	l := make([]rune, len(w.plain))
+	for j, i := range w.plain {
+		l[j] = unicode.ToLower(w.r[i])
+	}
+
+	return string(l)
+}
+
+func (w caseWord) lower() string {
+	r := append([]rune(nil), w.r...)
+	for _, i := range w.plain {
+		r[i] = unicode.ToLower(r[i])
+	}
+
+	return string(r)
+}
+
+func (w caseWord) capitalize() string {
+	// a word that starts with braces or math is protected as a whole
+	if len(w.plain) == 0 || strings.ContainsAny(string(w.r[:w.plain[0]]), "{$\\") {
+		return w.s
+	}
+
+	r := append([]rune(nil), w.r...)
+	r[w.plain[0]] = unicode.ToUpper(r[w.plain[0]])
+
+	return string(r)
+}
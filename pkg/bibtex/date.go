@@ -0,0 +1,268 @@
+package bibtex
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// edtfDate matches ISO 8601 dates with the EDTF qualifiers for uncertain
+// (?) and approximate (~, %) dates that biblatex understands.
+var edtfDate = regexp.MustCompile(`^(-?\d{4})(?:-(\d{2})(?:-(\d{2}))?)?([?~%]?)$`)
+
+// date is a single (possibly partial) date. Zero means unknown.
+type date struct {
+	year  int
+	month int
+	day   int
+}
+
+// dateRange is a biblatex date, which may be a range. An open end (as in
+// "2020/..") is a zero date.
+type dateRange struct {
+	start   date
+	end     date
+	isRange bool
+}
+
+func parseDate(s string) (date, bool) {
+	m := edtfDate.FindStringSubmatch(s)
+	if m == nil {
+		return date{}, false
+	}
+
+	var d date
+	d.year, _ = strconv.Atoi(m[1])
+	d.month, _ = strconv.Atoi(m[2])
+	d.day, _ = strconv.Atoi(m[3])
+
+	if d.month > 12 || (m[2] != "" && d.month < 1) || d.day > 31 || (m[3] != "" && d.day < 1) {
+		return date{}, false
+	}
+
+	return d, true
+}
+
+// parseDateRange parses a biblatex date field: a date or a range of two
+// dates separated by "/", where either end may be open ("" or "..").
+func parseDateRange(s string) (dateRange, bool) {
+	s = strings.TrimSpace(s)
+
+	parts := strings.Split(s, "/")
+	switch len(parts) {
+	case 1:
+		d, ok := parseDate(parts[0])
+		return dateRange{start: d}, ok
+	case 2:
+	default:
+		return dateRange{}, false
+	}
+
+	r := dateRange{isRange: true}
+	var ok bool
+
+	if parts[0] != "" && parts[0] != ".." {
+		if r.start, ok = parseDate(parts[0]); !ok {
+			return dateRange{}, false
+		}
+	}
+
+	if parts[1] != "" && parts[1] != ".." {
+		if r.end, ok = parseDate(parts[1]); !ok {
+			return dateRange{}, false
+		}
+	}
+
+	if r.start.year == 0 {
+		// biblatex needs a start date
+		return dateRange{}, false
+	}
+
+	return r, true
+}
+
+func (d date) String() string {
+	switch {
+	case d.day != 0:
+		return fmt.Sprintf("%04d-%02d-%02d", d.year, d.month, d.day)
+	case d.month != 0:
+		return fmt.Sprintf("%04d-%02d", d.year, d.month)
+	default:
+		return fmt.Sprintf("%04d", d.year)
+	}
+}
+
+func (r dateRange) String() string {
+	if !r.isRange {
+		return r.start.String()
+	}
+
+	if r.end.year == 0 {
+		return r.start.String() + "/.."
+	}
+
+	return r.start.String() + "/" + r.end.String()
+}
+
+var dateYear = regexp.MustCompile(`^\d{4}$`)
+
+// NormalizeDate converts between the biblatex date field and the BibTeX
+// year and month fields, depending on the style: biblatex gets a date
+// (e.g., "2021-06" or "2021-06/2021-07"), BibTeX styles get year and month.
+// If an entry has both, we check that they agree. urldate fields are
+// validated and, for ACM, turned into lastaccessed.
+func NormalizeDate(style string) func(e Element) Element {
+	return func(e Element) Element {
+		if val, ok := e.Tags["urldate"]; ok {
+			e = normalizeURLDate(e, val, style)
+		}
+
+		var r dateRange
+		hasDate := false
+
+		if val, ok := e.Tags["date"]; ok {
+			s, ok := unquote(val)
+			if !ok {
+				s = val
+			}
+
+			if r, hasDate = parseDateRange(s); !hasDate {
+				warnf(e, "invalid date %s, keeping it and any year and month", val)
+				return e
+			}
+		}
+
+		year := ""
+		if val, ok := e.Tags["year"]; ok {
+			if s, ok := unquote(val); ok {
+				year = s
+			} else {
+				year = val
+			}
+		}
+
+		// a month that we cannot parse is kept for NormalizeMonth to report
+		var months []int
+		validMonth := true
+		if val, ok := e.Tags["month"]; ok {
+			months, validMonth = parseMonths(val)
+		}
+
+		if hasDate {
+			checkDate(e, r, year, months)
+		}
+
+		if style == StyleBibLaTeX {
+			if !hasDate {
+				if !dateYear.MatchString(year) {
+					// e.g., "2021a" or "in press", biblatex has to take the year
+					if year != "" {
+						warnf(e, "year %s is not a date, keeping year and month", year)
+					}
+					return e
+				}
+
+				// e.g., "Spring", the date would lose it
+				if !validMonth {
+					return e
+				}
+
+				r.start.year, _ = strconv.Atoi(year)
+				if len(months) > 0 {
+					r.start.month = months[0]
+				}
+				if len(months) > 1 {
+					r.isRange = true
+					r.end = date{year: r.start.year, month: months[1]}
+				}
+
+				e.Tags["date"] = quote(r.String())
+			}
+
+			delete(e.Tags, "year")
+			if validMonth {
+				delete(e.Tags, "month")
+			}
+
+			return e
+		}
+
+		if !hasDate {
+			return e
+		}
+
+		if year == "" {
+			if r.isRange && r.end.year != 0 && r.end.year != r.start.year {
+				e.Tags["year"] = quote(fmt.Sprintf("%d--%d", r.start.year, r.end.year))
+			} else {
+				e.Tags["year"] = strconv.Itoa(r.start.year)
+			}
+		}
+
+		if _, ok := e.Tags["month"]; !ok && r.start.month != 0 {
+			months := []int{r.start.month}
+			if r.isRange && r.end.month != 0 && r.end.month != r.start.month {
+				months = append(months, r.end.month)
+			}
+			e.Tags["month"] = formatMonths(e, style, months)
+		}
+
+		delete(e.Tags, "date")
+
+		return e
+	}
+}
+
+// checkDate reports when year and month do not agree with the date.
+func checkDate(e Element, r dateRange, year string, months []int) {
+	if year != "" && year != strconv.Itoa(r.start.year) {
+		warnf(e, "year %s does not match date %s", year, r)
+	}
+
+	if len(months) > 0 && r.start.month != 0 && months[0] != r.start.month {
+		warnf(e, "month %s does not match date %s", monthMacros[months[0]-1], r)
+	}
+}
+
+// monthName returns the English name of a month.
+func monthName(m int) string {
+	n := monthNames[m-1][0]
+	return strings.ToUpper(n[:1]) + n[1:]
+}
+
+// normalizeURLDate validates the urldate. ACM calls it lastaccessed and
+// wants it written out.
+func normalizeURLDate(e Element, val string, style string) Element {
+	s, ok := unquote(val)
+	if !ok {
+		s = val
+	}
+
+	d, ok := parseDate(strings.TrimSpace(s))
+	if !ok {
+		warnf(e, "invalid urldate %s", val)
+		return e
+	}
+
+	e.Tags["urldate"] = quote(d.String())
+
+	if style != StyleACM {
+		return e
+	}
+
+	if _, ok := e.Tags["lastaccessed"]; !ok {
+		switch {
+		case d.day != 0:
+			e.Tags["lastaccessed"] = quote(fmt.Sprintf("%s %d, %d", monthName(d.month), d.day, d.year))
+		case d.month != 0:
+			e.Tags["lastaccessed"] = quote(fmt.Sprintf("%s %d", monthName(d.month), d.year))
+		default:
+			e.Tags["lastaccessed"] = quote(strconv.Itoa(d.year))
+		}
+	}
+
+	delete(e.Tags, "urldate")
+
+	return e
+}
@@ -0,0 +1,208 @@
+package bibtex
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestParseDateRange(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string // the date as NormalizeDate writes it, empty if invalid
+	}{
+		{"2020", "2020"},
+		{"2020-06", "2020-06"},
+		{"2020-06-15", "2020-06-15"},
+		{" 2020-06-15 ", "2020-06-15"},
+
+		// EDTF qualifiers for uncertain and approximate dates
+		{"2020?", "2020"},
+		{"2020-06~", "2020-06"},
+		{"2020-06-15%", "2020-06-15"},
+
+		// ranges, the end may be open
+		{"2020-06/2020-07", "2020-06/2020-07"},
+		{"2020-06-29/2020-07-03", "2020-06-29/2020-07-03"},
+		{"2020/..", "2020/.."},
+		{"2020/", "2020/.."},
+		{"2020-06/..", "2020-06/.."},
+
+		// biblatex needs a start date
+		{"../2020", ""},
+		{"/2020", ""},
+
+		{"2020-13", ""},
+		{"2020-00", ""},
+		{"2020-06-32", ""},
+		{"2020-6", ""},
+		{"2020/2021/2022", ""},
+		{"June 2020", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		r, ok := parseDateRange(tt.in)
+
+		got := ""
+		if ok {
+			got = r.String()
+		}
+
+		if got != tt.want {
+			t.Errorf("parseDateRange(%q) = %q (%v), want %q", tt.in, got, ok, tt.want)
+		}
+	}
+}
+
+func TestNormalizeDate(t *testing.T) {
+	tests := []struct {
+		name  string
+		style string
+		in    map[string]string
+		want  map[string]string
+		warn  string
+	}{
+		{
+			name:  "year and month become a date",
+			style: StyleBibLaTeX,
+			in:    map[string]string{"year": "2021", "month": "jun"},
+			want:  map[string]string{"date": `"2021-06"`},
+		},
+		{
+			name:  "month range becomes a date range",
+			style: StyleBibLaTeX,
+			in:    map[string]string{"year": `"2021"`, "month": `jun # "--" # jul`},
+			want:  map[string]string{"date": `"2021-06/2021-07"`},
+		},
+		{
+			name:  "open date range is kept",
+			style: StyleBibLaTeX,
+			in:    map[string]string{"date": `"2020/.."`},
+			want:  map[string]string{"date": `"2020/.."`},
+		},
+		{
+			name:  "year and month that agree with the date are dropped",
+			style: StyleBibLaTeX,
+			in:    map[string]string{"date": `"2021-06-15"`, "year": "2021", "month": "6"},
+			want:  map[string]string{"date": `"2021-06-15"`},
+		},
+		{
+			name:  "year that disagrees with the date",
+			style: StyleBibLaTeX,
+			in:    map[string]string{"date": `"2021-06"`, "year": "2020"},
+			want:  map[string]string{"date": `"2021-06"`},
+			warn:  "year 2020 does not match date 2021-06",
+		},
+		{
+			name:  "month that disagrees with the date",
+			style: StyleIEEE,
+			in:    map[string]string{"date": `"2021-06"`, "year": "2021", "month": "jul"},
+			want:  map[string]string{"year": "2021", "month": "jul"},
+			warn:  "month jul does not match date 2021-06",
+		},
+		{
+			name:  "date becomes year and month",
+			style: StyleIEEE,
+			in:    map[string]string{"date": `"2021-06-15"`},
+			want:  map[string]string{"year": "2021", "month": "jun"},
+		},
+		{
+			name:  "date range becomes a month range",
+			style: StyleACM,
+			in:    map[string]string{"date": `"2021-06-29/2021-07-02"`},
+			want:  map[string]string{"year": "2021", "month": `jun # "--" # jul`},
+		},
+		{
+			name:  "date range over years becomes a year range",
+			style: StyleIEEE,
+			in:    map[string]string{"date": `"2020/2021"`},
+			want:  map[string]string{"year": `"2020--2021"`},
+		},
+		{
+			name:  "open date range becomes its start",
+			style: StyleIEEE,
+			in:    map[string]string{"date": `"2020/.."`},
+			want:  map[string]string{"year": "2020"},
+		},
+		{
+			name:  "year and month without date are left alone",
+			style: StyleIEEE,
+			in:    map[string]string{"year": "2021", "month": "jun"},
+			want:  map[string]string{"year": "2021", "month": "jun"},
+		},
+		{
+			name:  "invalid date is kept",
+			style: StyleIEEE,
+			in:    map[string]string{"date": `"spring 2020"`, "year": "2020"},
+			want:  map[string]string{"date": `"spring 2020"`, "year": "2020"},
+			warn:  "invalid date",
+		},
+		{
+			name:  "bare date",
+			style: StyleBibLaTeX,
+			in:    map[string]string{"date": "2021"},
+			want:  map[string]string{"date": "2021"},
+		},
+		{
+			name:  "bare date becomes year",
+			style: StyleIEEE,
+			in:    map[string]string{"date": "2021"},
+			want:  map[string]string{"year": "2021"},
+		},
+		{
+			name:  "year that is not a date is kept with the month",
+			style: StyleBibLaTeX,
+			in:    map[string]string{"year": `"2021a"`, "month": "jun"},
+			want:  map[string]string{"year": `"2021a"`, "month": "jun"},
+			warn:  "year 2021a is not a date",
+		},
+		{
+			name:  "month that is not a month is kept with the year",
+			style: StyleBibLaTeX,
+			in:    map[string]string{"year": "2021", "month": "{Spring}"},
+			want:  map[string]string{"year": "2021", "month": "{Spring}"},
+		},
+		{
+			name:  "month that is not a month is kept with the date",
+			style: StyleBibLaTeX,
+			in:    map[string]string{"date": `"2021"`, "year": "2021", "month": "{Spring}"},
+			want:  map[string]string{"date": `"2021"`, "month": "{Spring}"},
+		},
+		{
+			name:  "urldate is validated",
+			style: StyleBibLaTeX,
+			in:    map[string]string{"urldate": `"2021-06-15"`, "date": `"2021"`},
+			want:  map[string]string{"urldate": `"2021-06-15"`, "date": `"2021"`},
+		},
+		{
+			name:  "invalid urldate is kept",
+			style: StyleBibLaTeX,
+			in:    map[string]string{"urldate": `"15.06.2021"`, "date": `"2021"`},
+			want:  map[string]string{"urldate": `"15.06.2021"`, "date": `"2021"`},
+			warn:  "invalid urldate",
+		},
+		{
+			name:  "urldate becomes lastaccessed for ACM",
+			style: StyleACM,
+			in:    map[string]string{"urldate": `"2021-06-15"`, "year": "2021"},
+			want:  map[string]string{"lastaccessed": `"June 15, 2021"`, "year": "2021"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Element{ID: "test", Tags: tt.in}
+
+			warned := captureWarnings(func() { e = NormalizeDate(tt.style)(e) })
+
+			if !maps.Equal(e.Tags, tt.want) {
+				t.Errorf("got %v, want %v", e.Tags, tt.want)
+			}
+
+			if (tt.warn == "" && warned != "") || !strings.Contains(warned, tt.warn) {
+				t.Errorf("warned %q, want %q", warned, tt.warn)
+			}
+		})
+	}
+}
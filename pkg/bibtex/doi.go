@@ -0,0 +1,105 @@
+package bibtex
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	// https://www.doi.org/doi_handbook/2_Numbering.html#2.2
+	doiSyntax = regexp.MustCompile(`^10\.\d{4,9}(\.\d+)*/\S+$`)
+	doiPrefix = regexp.MustCompile(`^(?i)(doi:\s*|https?://(dx\.)?doi\.org/)`)
+	// a DOI in free text such as a note or URL
+	doiInText = regexp.MustCompile(`(?i)(doi:\s*|https?://(dx\.)?doi\.org/)?(10\.\d{4,9}(\.\d+)*/[^\s"{},]+)`)
+	doiURL    = regexp.MustCompile(`^(?i)https?://(dx\.)?doi\.org/`)
+)
+
+// normalizeDOI turns the many ways to write a DOI into the bare, lowercase
+// DOI, e.g., "https://dx.doi.org/10.1145%2F123" becomes "10.1145/123".
+// DOIs are case-insensitive, so nothing is lost by lowercasing.
+func normalizeDOI(s string) string {
+	s = strings.Join(strings.Fields(s), "")
+	s = doiPrefix.ReplaceAllString(s, "")
+
+	if u, err := url.PathUnescape(s); err == nil {
+		s = u
+	}
+
+	// escaped underscores and the like
+	s = strings.ReplaceAll(s, `\`, "")
+
+	return strings.ToLower(s)
+}
+
+// CleanDOI normalizes and validates the doi field and deduplicates it with
+// the url and note fields: a DOI found only in the url or note is moved to
+// the doi field, a note that only repeats the DOI is removed, and the url is
+// set to the DOI link if it is missing.
+func CleanDOI(e Element) Element {
+	doi := ""
+
+	if val, ok := e.Tags["doi"]; ok {
+		s, ok := unquote(val)
+		if !ok {
+			s = val
+		}
+		doi = normalizeDOI(s)
+
+		if doi == "" {
+			delete(e.Tags, "doi")
+		} else if !doiSyntax.MatchString(doi) {
+			warnf(e, "malformed DOI %q", s)
+		}
+	}
+
+	// take the DOI from a doi.org link
+	doiLink := false
+	if u, ok := e.Tags["url"]; ok {
+		if s, ok := unquote(u); ok && doiURL.MatchString(strings.TrimSpace(s)) {
+			d := normalizeDOI(s)
+			doiLink = true
+
+			switch {
+			case doi == "":
+				doi = d
+			case d != doi:
+				warnf(e, "url %s points to a different DOI than %s", u, doi)
+				doiLink = false
+			}
+		}
+	}
+
+	// take the DOI from the note and drop the note if that's all it says
+	if n, ok := e.Tags["note"]; ok {
+		if s, ok := unquote(n); ok {
+			if m := doiInText.FindStringSubmatchIndex(s); m != nil {
+				d := normalizeDOI(strings.TrimRight(s[m[6]:m[7]], ".;:"))
+
+				if doi == "" {
+					doi = d
+				}
+
+				if d == doi && strings.TrimSpace(strings.TrimRight(s[:m[0]]+s[m[1]:], " .,;")) == "" {
+					delete(e.Tags, "note")
+				}
+			}
+		}
+	}
+
+	if doi == "" {
+		return e
+	}
+
+	e.Tags["doi"] = quote(doi)
+
+	// add the DOI link if there is no url, or write it the same way if the
+	// url already is one
+	if u, ok := e.Tags["url"]; !ok || u == "" || u == `""` || doiLink {
+		if doiSyntax.MatchString(doi) {
+			e.Tags["url"] = quote("https://doi.org/" + doi)
+		}
+	}
+
+	return e
+}
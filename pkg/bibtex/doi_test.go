@@ -0,0 +1,93 @@
+package bibtex
+
+import (
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeDOI(t *testing.T) {
+	for in, want := range map[string]string{
+		"10.1145/3464298.3493394":             "10.1145/3464298.3493394",
+		"doi:10.1145/123":                     "10.1145/123",
+		"DOI: 10.1109/TPDS.2020.123":          "10.1109/tpds.2020.123",
+		"https://doi.org/10.1145/123":         "10.1145/123",
+		"http://dx.doi.org/10.1145%2F123":     "10.1145/123",
+		"HTTPS://DX.DOI.ORG/10.1145%2f123":    "10.1145/123",
+		"10.1002/(SICI)1097-4571%28199806%29": "10.1002/(sici)1097-4571(199806)",
+		`10.1016/j.future\_2020`:              "10.1016/j.future_2020",
+		" 10.1145/ 123\n":                     "10.1145/123",
+	} {
+		if got := normalizeDOI(in); got != want {
+			t.Errorf("normalizeDOI(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCleanDOI(t *testing.T) {
+	tests := []struct {
+		name string
+		in   map[string]string
+		want map[string]string
+		warn string
+	}{
+		{
+			name: "doi field gets a url",
+			in:   map[string]string{"doi": `"https://dx.doi.org/10.1145%2F123"`},
+			want: map[string]string{"doi": `"10.1145/123"`, "url": `"https://doi.org/10.1145/123"`},
+		},
+		{
+			name: "doi from url",
+			in:   map[string]string{"url": `"http://dx.doi.org/10.1145%2F123"`},
+			want: map[string]string{"doi": `"10.1145/123"`, "url": `"https://doi.org/10.1145/123"`},
+		},
+		{
+			name: "other url is kept",
+			in:   map[string]string{"doi": `"10.1145/123"`, "url": `"https://example.com/paper.pdf"`},
+			want: map[string]string{"doi": `"10.1145/123"`, "url": `"https://example.com/paper.pdf"`},
+		},
+		{
+			name: "url with another doi",
+			in:   map[string]string{"doi": `"10.1145/123"`, "url": `"https://doi.org/10.1145/456"`},
+			want: map[string]string{"doi": `"10.1145/123"`, "url": `"https://doi.org/10.1145/456"`},
+			warn: "points to a different DOI",
+		},
+		{
+			name: "note with only the doi is dropped",
+			in:   map[string]string{"note": `"doi: 10.1145/123."`, "url": `"https://example.com"`},
+			want: map[string]string{"doi": `"10.1145/123"`, "url": `"https://example.com"`},
+		},
+		{
+			name: "note with more than the doi is kept",
+			in:   map[string]string{"doi": `"10.1145/123"`, "note": `"Extended version of doi:10.1145/456"`},
+			want: map[string]string{"doi": `"10.1145/123"`, "note": `"Extended version of doi:10.1145/456"`, "url": `"https://doi.org/10.1145/123"`},
+		},
+		{
+			name: "malformed doi gets no url",
+			in:   map[string]string{"doi": `"1145/123"`},
+			want: map[string]string{"doi": `"1145/123"`},
+			warn: "malformed DOI",
+		},
+		{
+			name: "empty doi is removed",
+			in:   map[string]string{"doi": `""`},
+			want: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Element{ID: "test", Tags: tt.in}
+
+			warned := captureWarnings(func() { e = CleanDOI(e) })
+
+			if !maps.Equal(e.Tags, tt.want) {
+				t.Errorf("got %v, want %v", e.Tags, tt.want)
+			}
+
+			if (tt.warn == "") != (warned == "") || !strings.Contains(warned, tt.warn) {
+				t.Errorf("warned %q, want %q", warned, tt.warn)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package bibtex
+
+import (
+	"strings"
+	"unicode"
+)
+
+// verbatimFields are fields that are printed verbatim (or are not printed
+// at all), so LaTeX special characters must not be escaped. This includes
+// fields that hold entry keys, which must match the keys exactly.
+var verbatimFields = map[string]struct{}{
+	"url":      {},
+	"urlraw":   {},
+	"doi":      {},
+	"eprint":   {},
+	"file":     {},
+	"pdf":      {},
+	"urldate":  {},
+	"crossref": {},
+	"xref":     {},
+	"ids":      {},
+	"related":  {},
+	"entryset": {},
+}
+
+// verbatimCommands are commands whose first argument is verbatim.
+var verbatimCommands = map[string]struct{}{
+	"url":       {},
+	"href":      {},
+	"path":      {},
+	"nolinkurl": {},
+}
+
+// EscapeSpecial escapes the LaTeX special characters &, %, #, and _ (which
+// end up in titles copied from web pages) so that they don't break
+// compilation. Math mode, existing commands, and fields such as url and doi
+// are left alone. Unbalanced $ and braces are reported.
+func EscapeSpecial(e Element) Element {
+	for key, val := range e.Tags {
+		if _, ok := verbatimFields[key]; ok {
+			continue
+		}
+
+		e.Tags[key] = mapStrings(val, func(s string) string {
+			return escapeSpecial(e, key, s)
+		})
+	}
+
+	return e
+}
+
+// mapStrings applies f to the contents of every quoted or braced string in
+// a value, which may be a concatenation such as `jun # "--" # jul`.
+func mapStrings(val string, f func(string) string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(val); i++ {
+		c := val[i]
+		if c != '"' && c != '{' {
+			b.WriteByte(c)
+			continue
+		}
+
+		// find the end of the string, braces nest in both kinds of strings
+		depth := 0
+		end := -1
+		for j := i + 1; j < len(val) && end < 0; j++ {
+			switch {
+			case val[j] == '{':
+				depth++
+			case val[j] == '}' && depth == 0 && c == '{':
+				end = j
+			case val[j] == '}':
+				depth--
+			case val[j] == '"' && depth == 0 && c == '"':
+				end = j
+			}
+		}
+
+		if end < 0 {
+			// unterminated, leave the rest as it is
+			b.WriteString(val[i:])
+			break
+		}
+
+		b.WriteByte(c)
+		b.WriteString(f(val[i+1 : end]))
+		b.WriteByte(val[end])
+		i = end
+	}
+
+	return b.String()
+}
+
+func escapeSpecial(e Element, key string, s string) string {
+	var b strings.Builder
+
+	r := []rune(s)
+	math := false
+	depth := 0
+	unbalanced := false
+
+	for i := 0; i < len(r); i++ {
+		c := r[i]
+
+		switch c {
+		case '\\':
+			// copy the command (or escaped character) as it is
+			j := i + 1
+			for j < len(r) && unicode.IsLetter(r[j]) {
+				j++
+			}
+			if j == i+1 && j < len(r) {
+				j++
+			}
+			b.WriteString(string(r[i:j]))
+
+			name := string(r[i+1 : j])
+			i = j - 1
+
+			if _, ok := verbatimCommands[name]; ok && j < len(r) && r[j] == '{' {
+				// copy the argument as it is, too
+				k, d := j, 0
+				for ; k < len(r); k++ {
+					if r[k] == '{' {
+						d++
+					} else if r[k] == '}' {
+						d--
+						if d == 0 {
+							break
+						}
+					}
+				}
+				b.WriteString(string(r[j:min(k+1, len(r))]))
+				i = k
+			}
+
+			continue
+		case '$':
+			math = !math
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth < 0 {
+				unbalanced = true
+			}
+		case '%', '&', '#':
+			b.WriteRune('\\')
+		case '_':
+			if !math {
+				b.WriteRune('\\')
+			}
+		}
+
+		b.WriteRune(c)
+	}
+
+	if math {
+		warnf(e, "unbalanced $ in %s", key)
+	}
+
+	if unbalanced || depth != 0 {
+		warnf(e, "unbalanced braces in %s", key)
+	}
+
+	return b.String()
+}
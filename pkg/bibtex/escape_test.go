@@ -0,0 +1,62 @@
+package bibtex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEscapeSpecial(t *testing.T) {
+	tests := []struct {
+		key  string
+		in   string
+		want string
+		warn string
+	}{
+		{"title", `"Tips & Tricks for 100% of C# Users"`, `"Tips \& Tricks for 100\% of C\# Users"`, ""},
+		{"title", `{Tips \& Tricks}`, `{Tips \& Tricks}`, ""},
+		{"title", `"Snake_case and $x_i$ in math"`, `"Snake\_case and $x_i$ in math"`, ""},
+		{"note", `"See \url{https://example.com/a_b#c}"`, `"See \url{https://example.com/a_b#c}"`, ""},
+		{"title", `"A {B_C} D"`, `"A {B\_C} D"`, ""},
+		{"title", `"Costs in $ and euros"`, `"Costs in $ and euros"`, "unbalanced"},
+
+		// fields that are used verbatim or hold entry keys
+		{"url", `"https://example.com/a_b?c=1&d=2#e"`, `"https://example.com/a_b?c=1&d=2#e"`, ""},
+		{"doi", `"10.1016/j.future_2020"`, `"10.1016/j.future_2020"`, ""},
+		{"crossref", `{conf_2020}`, `{conf_2020}`, ""},
+		{"xref", `"conf_2020"`, `"conf_2020"`, ""},
+		{"ids", `"smith_2020,smith&jones"`, `"smith_2020,smith&jones"`, ""},
+		{"related", `"smith_2020"`, `"smith_2020"`, ""},
+		{"entryset", `"a_1,b_2"`, `"a_1,b_2"`, ""},
+	}
+
+	for _, tt := range tests {
+		e := Element{ID: "test", Tags: map[string]string{tt.key: tt.in}}
+
+		warned := captureWarnings(func() { e = EscapeSpecial(e) })
+
+		if got := e.Tags[tt.key]; got != tt.want {
+			t.Errorf("EscapeSpecial(%s = %s) = %s, want %s", tt.key, tt.in, got, tt.want)
+		}
+
+		if (tt.warn == "" && warned != "") || (tt.warn != "" && !strings.Contains(warned, tt.warn)) {
+			t.Errorf("EscapeSpecial(%s = %s) warned %q, want %q", tt.key, tt.in, warned, tt.warn)
+		}
+	}
+}
+
+func TestMapStrings(t *testing.T) {
+	upper := strings.ToUpper
+
+	for in, want := range map[string]string{
+		`"abc"`:                 `"ABC"`,
+		`{abc {def}}`:           `{ABC {DEF}}`,
+		`jun # "--" # jul`:      `jun # "--" # jul`,
+		`"a" # x # {b}`:         `"A" # x # {B}`,
+		`"a {"} b"`:             `"A {"} B"`,
+		`"unterminated {brace"`: `"unterminated {brace"`,
+	} {
+		if got := mapStrings(in, upper); got != want {
+			t.Errorf("mapStrings(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
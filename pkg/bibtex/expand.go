@@ -0,0 +1,186 @@
+package bibtex
+
+import (
+	"regexp"
+	"strings"
+)
+
+// wordExpansions maps abbreviations (e.g., "Appl.") to the words they can
+// stand for in the IEEE lists (e.g., "Applied" and "Applications").
+var wordExpansions = invertShortforms(ieeeShortforms, ieeeTitleShortforms)
+
+// invertShortforms compiles the expansions of the IEEE tables. Short forms
+// that do not end in a period, such as "1st" for "First" or the removed
+// "of", cannot be told apart from normal words and are skipped.
+func invertShortforms(tables ...*map[string]string) *abbreviations {
+	patterns := make(map[string][]string)
+
+	for _, table := range tables {
+		for long, short := range *table {
+			long, short = strings.TrimSpace(long), strings.TrimSpace(short)
+			if !strings.HasSuffix(short, ".") {
+				continue
+			}
+
+			patterns[short] = append(patterns[short], long)
+		}
+	}
+
+	return compileAbbreviations(patterns)
+}
+
+func containsFold(words []string, w string) bool {
+	for _, v := range words {
+		if strings.EqualFold(v, w) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExpandAbbreviations is the opposite of ShortenBooktitle and ShortenAll:
+// it writes abbreviated words in the booktitle, journal, and title out in
+// full. Venues from the abbreviation lists and the venue database are
+// expanded as a whole, since only they know the words that abbreviating
+// left out (e.g., "of" in "J. Appl. Phys."). Abbreviations that can stand
+// for several words (e.g., "Appl." for "Applied" or "Applications") are
+// resolved from their position, see resolveExpansion. Abbreviations that
+// remain ambiguous are reported and kept, and so are expansions that
+// probably miss a left out word.
+func ExpandAbbreviations(lists AbbreviationLists, venues Venues) func(e Element) Element {
+	return func(e Element) Element {
+		for _, key := range []string{"booktitle", "journal", "title"} {
+			val, ok := e.Tags[key]
+			if !ok {
+				continue
+			}
+
+			if key != "title" && (lists.expandFromList(e, key) || venues.expandVenue(e, key)) {
+				continue
+			}
+
+			expanded := make(map[string]struct{})
+
+			e.Tags[key] = mapStrings(val, func(s string) string {
+				return wordExpansions.replace(s, func(m abbreviationMatch) (string, bool) {
+					long, ok := resolveExpansion(m.forms, m.followed)
+					if !ok {
+						warnf(e, "ambiguous abbreviation %s in %s could be %s", m.text, key, strings.Join(m.forms, " or "))
+						return long, ok
+					}
+
+					expanded[strings.ToLower(long)] = struct{}{}
+
+					return long, ok
+				})
+			})
+
+			for _, gap := range expansionGap.FindAllStringSubmatch(e.Tags[key], -1) {
+				if _, ok := expanded[strings.ToLower(gap[1])]; !ok {
+					continue
+				}
+
+				if _, ok := smallWords[strings.ToLower(gap[2])]; ok {
+					continue
+				}
+
+				// AddProcOf writes the booktitle prefix out in full
+				if key == "booktitle" && strings.EqualFold(gap[1], "Proceedings") {
+					continue
+				}
+
+				warnf(e, "expanded %s %s may be missing a word such as \"of\" after %s", key, e.Tags[key], gap[1])
+			}
+		}
+
+		return e
+	}
+}
+
+// expansionGap finds nouns that are usually followed by a preposition (e.g.,
+// "Journal of", "Conference on") together with the word after them.
+// Abbreviations leave the preposition out, so if it is missing after
+// expanding, the expansion is probably wrong.
+var expansionGap = regexp.MustCompile(`(?i)\b(Annals|Archives?|Bulletin|Conference|Journal|Proceedings|Symposium|Transactions|Workshop)\s+([a-z]+)\b`)
+
+// expandVenue replaces an abbreviated venue from the venue database (one
+// that matches its patterns or its short name) with its full name.
+func (venues Venues) expandVenue(e Element, key string) bool {
+	s, ok := unquote(e.Tags[key])
+	if !ok {
+		return false
+	}
+
+	v := venues.Match(s)
+	if v == nil {
+		for _, c := range venues {
+			if c.Short != "" && normalizeVenue(c.Short) == normalizeVenue(s) {
+				v = c
+				break
+			}
+		}
+	}
+
+	if v == nil {
+		return false
+	}
+
+	e.Tags[key] = quote(escapeSpecial(e, key, v.Name))
+
+	return true
+}
+
+// resolveExpansion picks the expansion that fits the position of the word:
+// an adjective or singular noun before another word (e.g., "Applied" in
+// "Appl. Phys.") and a noun at the end (e.g., "Applications" in "Comput.
+// Appl."). At the end, the plural wins over its own singular (e.g.,
+// "Letters" over "Letter").
+func resolveExpansion(candidates []string, followed bool) (string, bool) {
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+
+	var modifiers, nouns []string
+	for _, c := range candidates {
+		switch {
+		case isAdjective(c):
+			modifiers = append(modifiers, c)
+		case strings.HasSuffix(c, "s"):
+			nouns = append(nouns, c)
+		default:
+			modifiers = append(modifiers, c)
+			nouns = append(nouns, c)
+		}
+	}
+
+	if followed {
+		if len(modifiers) == 1 {
+			return modifiers[0], true
+		}
+		return "", false
+	}
+
+	switch {
+	case len(nouns) == 1:
+		return nouns[0], true
+	case len(nouns) == 2 && strings.EqualFold(nouns[0]+"s", nouns[1]):
+		return nouns[1], true
+	case len(nouns) == 2 && strings.EqualFold(nouns[1]+"s", nouns[0]):
+		return nouns[0], true
+	}
+
+	return "", false
+}
+
+var adjectiveSuffixes = []string{"al", "an", "ar", "ary", "ed", "ic", "ive", "ous"}
+
+// isAdjective guesses from its ending whether a word is an adjective.
+func isAdjective(w string) bool {
+	w = strings.ToLower(w)
+	for _, suffix := range adjectiveSuffixes {
+		if strings.HasSuffix(w, suffix) {
+			return true
+		}
+	}
+	return false
+}
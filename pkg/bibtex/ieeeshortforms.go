@@ -163,7 +163,7 @@ var (
 		" Informatics ":            " Inform. ",
 		" Knowledge ":              " Knowl. ",
 		" Laboratory ":             " Lab. ",
-		" Laboratoryies ":          " Lab. ",
+		" Laboratories ":           " Lab. ",
 		" Mathematical ":           " Math. ",
 		" Language ":               " Lang. ",
 		" Mathematics ":            " Math. ",
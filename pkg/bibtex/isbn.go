@@ -0,0 +1,225 @@
+package bibtex
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//go:embed data/isbnranges.txt
+var isbnRangesData string
+
+// isbnRange says how many of the next digits after a prefix belong to the
+// next element of the ISBN, depending on the seven digits after the prefix.
+type isbnRange struct {
+	first  int
+	last   int
+	length int
+}
+
+var isbnRanges = parseISBNRanges(isbnRangesData)
+
+func parseISBNRanges(data string) map[string][]isbnRange {
+	ranges := make(map[string][]isbnRange)
+
+	s := bufio.NewScanner(strings.NewReader(data))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var prefix string
+		var r isbnRange
+		if _, err := fmt.Sscanf(line, "%s %d-%d %d", &prefix, &r.first, &r.last, &r.length); err != nil {
+			panic(fmt.Sprintf("invalid ISBN range %q: %s", line, err))
+		}
+
+		ranges[prefix] = append(ranges[prefix], r)
+	}
+
+	return ranges
+}
+
+var (
+	identifierSeparator = regexp.MustCompile(`\s*(,|;|\s+and\s+)\s*`)
+	identifierLabel     = regexp.MustCompile(`^(?i)(isbn|issn)(-1[03])?:?\s*`)
+	identifierSpacing   = strings.NewReplacer("-", "", " ", "", "‐", "", "–", "", "~", "")
+)
+
+// CleanISBN validates the check digits of the ISBNs in the isbn field,
+// converts ISBN-10 to ISBN-13, and hyphenates them. Invalid ISBNs are
+// reported and kept as they are.
+func CleanISBN(e Element) Element {
+	return cleanIdentifiers(e, "isbn", func(s string) (string, bool) {
+		switch len(s) {
+		case 10:
+			if !validISBN10(s) {
+				return s, false
+			}
+			s = "978" + s[:9]
+			s += isbnCheckDigit(s)
+		case 13:
+			if !validISBN13(s) {
+				return s, false
+			}
+		default:
+			return s, false
+		}
+
+		return hyphenateISBN(s), true
+	})
+}
+
+// CleanISSN validates the check digits of the ISSNs in the issn field and
+// writes them as "1234-5679". Invalid ISSNs are reported and kept as they
+// are.
+func CleanISSN(e Element) Element {
+	return cleanIdentifiers(e, "issn", func(s string) (string, bool) {
+		if len(s) != 8 || !validISSN(s) {
+			return s, false
+		}
+
+		return s[:4] + "-" + s[4:], true
+	})
+}
+
+// cleanIdentifiers applies clean to every identifier in a field that may
+// have several of them (e.g., for print and electronic versions).
+func cleanIdentifiers(e Element, key string, clean func(string) (string, bool)) Element {
+	val, ok := e.Tags[key]
+	if !ok {
+		return e
+	}
+
+	s, ok := unquote(val)
+	if !ok {
+		s = val
+	}
+
+	ids := identifierSeparator.Split(strings.TrimSpace(s), -1)
+	for i, id := range ids {
+		digits := strings.ToUpper(identifierSpacing.Replace(identifierLabel.ReplaceAllString(id, "")))
+
+		c, ok := clean(digits)
+		if !ok {
+			warnf(e, "invalid %s %s", strings.ToUpper(key), id)
+			continue
+		}
+
+		ids[i] = c
+	}
+
+	e.Tags[key] = quote(strings.Join(ids, ", "))
+
+	return e
+}
+
+func validISBN10(s string) bool {
+	sum := 0
+	for i, c := range s {
+		d := int(c - '0')
+		switch {
+		case c == 'X' && i == 9:
+			d = 10
+		case c < '0' || c > '9':
+			return false
+		}
+		sum += (10 - i) * d
+	}
+
+	return sum%11 == 0
+}
+
+func validISBN13(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return isbnCheckDigit(s[:12]) == s[12:]
+}
+
+// isbnCheckDigit computes the check digit for the first 12 digits of an
+// ISBN-13.
+func isbnCheckDigit(s string) string {
+	sum := 0
+	for i, c := range s {
+		d := int(c - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+
+	return strconv.Itoa((10 - sum%10) % 10)
+}
+
+func validISSN(s string) bool {
+	sum := 0
+	for i, c := range s {
+		d := int(c - '0')
+		switch {
+		case c == 'X' && i == 7:
+			d = 10
+		case c < '0' || c > '9':
+			return false
+		}
+		sum += (8 - i) * d
+	}
+
+	return sum%11 == 0
+}
+
+// hyphenateISBN splits an ISBN-13 into prefix, registration group,
+// registrant, publication, and check digit, if we know the ranges for its
+// group.
+func hyphenateISBN(s string) string {
+	prefix := s[:3]
+
+	group := isbnElementLength(prefix, s[3:])
+	if group == 0 {
+		return s
+	}
+
+	groupPrefix := prefix + "-" + s[3:3+group]
+
+	registrant := isbnElementLength(groupPrefix, s[3+group:])
+	if registrant == 0 {
+		return s
+	}
+
+	return strings.Join([]string{
+		prefix,
+		s[3 : 3+group],
+		s[3+group : 3+group+registrant],
+		s[3+group+registrant : 12],
+		s[12:],
+	}, "-")
+}
+
+// isbnElementLength looks up how many digits of rest belong to the element
+// that follows prefix.
+func isbnElementLength(prefix string, rest string) int {
+	ranges, ok := isbnRanges[prefix]
+	if !ok {
+		return 0
+	}
+
+	n, err := strconv.Atoi((rest + "0000000")[:7])
+	if err != nil {
+		return 0
+	}
+
+	for _, r := range ranges {
+		if n >= r.first && n <= r.last {
+			return r.length
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,59 @@
+package bibtex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCleanIdentifiers(t *testing.T) {
+	tests := []struct {
+		clean   func(e Element) Element
+		key     string
+		in      string
+		want    string
+		invalid bool
+	}{
+		// ISBN-10 becomes ISBN-13 with a new check digit
+		{CleanISBN, "isbn", `"0-306-40615-2"`, `"978-0-306-40615-7"`, false},
+		{CleanISBN, "isbn", `"0306406152"`, `"978-0-306-40615-7"`, false},
+		{CleanISBN, "isbn", `"ISBN 0-8044-2957-X"`, `"978-0-8044-2957-3"`, false},
+		{CleanISBN, "isbn", `"080442957x"`, `"978-0-8044-2957-3"`, false},
+
+		// ISBN-13 is hyphenated where we know the ranges
+		{CleanISBN, "isbn", `9783161484100`, `"978-3-16-148410-0"`, false},
+		{CleanISBN, "isbn", `"ISBN-13: 978 3 16 148410 0"`, `"978-3-16-148410-0"`, false},
+		{CleanISBN, "isbn", `"978‐3‐16‐148410‐0"`, `"978-3-16-148410-0"`, false},
+		{CleanISBN, "isbn", `"9786001234569"`, `"9786001234569"`, false},
+
+		// several ISBNs, e.g., for print and electronic versions
+		{CleanISBN, "isbn", `"0-306-40615-2; 9783161484100"`, `"978-0-306-40615-7, 978-3-16-148410-0"`, false},
+		{CleanISBN, "isbn", `"0-306-40615-2 and 9783161484100"`, `"978-0-306-40615-7, 978-3-16-148410-0"`, false},
+
+		// wrong check digits and lengths are kept and reported
+		{CleanISBN, "isbn", `"0-306-40615-3"`, `"0-306-40615-3"`, true},
+		{CleanISBN, "isbn", `"978-3-16-148410-1"`, `"978-3-16-148410-1"`, true},
+		{CleanISBN, "isbn", `"978-3-16"`, `"978-3-16"`, true},
+		{CleanISBN, "isbn", `"0-306-40615-3, 9783161484100"`, `"0-306-40615-3, 978-3-16-148410-0"`, true},
+
+		{CleanISSN, "issn", `"03785955"`, `"0378-5955"`, false},
+		{CleanISSN, "issn", `"ISSN 0378-5955"`, `"0378-5955"`, false},
+		{CleanISSN, "issn", `"2434-561x"`, `"2434-561X"`, false},
+		{CleanISSN, "issn", `"0378-5955, 2049-3630"`, `"0378-5955, 2049-3630"`, false},
+		{CleanISSN, "issn", `"0378-5954"`, `"0378-5954"`, true},
+		{CleanISSN, "issn", `"0378-59"`, `"0378-59"`, true},
+	}
+
+	for _, tt := range tests {
+		e := Element{ID: "test", Tags: map[string]string{tt.key: tt.in}}
+
+		warned := captureWarnings(func() { e = tt.clean(e) })
+
+		if got := e.Tags[tt.key]; got != tt.want {
+			t.Errorf("%s = %s: got %s, want %s", tt.key, tt.in, got, tt.want)
+		}
+
+		if invalid := strings.Contains(warned, "invalid "+strings.ToUpper(tt.key)); invalid != tt.invalid {
+			t.Errorf("%s = %s: warned %q", tt.key, tt.in, warned)
+		}
+	}
+}
@@ -0,0 +1,217 @@
+package bibtex
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+//go:embed data/ltwa.txt
+var ltwaData string
+
+// notAbbreviated marks words in the LTWA that are kept as they are.
+const notAbbreviated = "n.a."
+
+// ltwaPattern is a prefix ("comput-") or suffix ("-graphy") from the LTWA
+// without the hyphen, and its abbreviation.
+type ltwaPattern struct {
+	pattern string
+	abbrev  string
+}
+
+// ltwa is a List of Title Word Abbreviations.
+type ltwa struct {
+	words    map[string]string
+	prefixes []ltwaPattern
+	suffixes []ltwaPattern
+}
+
+var titleWords = parseLTWA(ltwaData)
+
+func parseLTWA(data string) *ltwa {
+	l := &ltwa{words: make(map[string]string)}
+
+	s := bufio.NewScanner(strings.NewReader(data))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		f := strings.Fields(line)
+		if len(f) != 2 {
+			panic(fmt.Sprintf("invalid LTWA entry %q", line))
+		}
+
+		word, abbrev := strings.ToLower(f[0]), strings.ToLower(f[1])
+
+		switch {
+		case strings.HasPrefix(word, "-"):
+			l.suffixes = append(l.suffixes, ltwaPattern{strings.TrimPrefix(word, "-"), strings.TrimPrefix(abbrev, "-")})
+		case strings.HasSuffix(word, "-"):
+			l.prefixes = append(l.prefixes, ltwaPattern{strings.TrimSuffix(word, "-"), abbrev})
+		default:
+			l.words[word] = abbrev
+		}
+	}
+
+	// longer patterns take precedence
+	for _, p := range [][]ltwaPattern{l.prefixes, l.suffixes} {
+		sort.SliceStable(p, func(i, j int) bool {
+			return len(p[i].pattern) > len(p[j].pattern)
+		})
+	}
+
+	return l
+}
+
+// abbreviate returns the abbreviation of a lowercase word, or false if the
+// word is not abbreviated.
+func (l *ltwa) abbreviate(word string) (string, bool) {
+	abbrev, ok := l.words[word]
+
+	if !ok {
+		for _, p := range l.prefixes {
+			if strings.HasPrefix(word, p.pattern) {
+				abbrev, ok = p.abbrev, true
+				break
+			}
+		}
+	}
+
+	if !ok {
+		for _, p := range l.suffixes {
+			if len(word) > len(p.pattern) && strings.HasSuffix(word, p.pattern) {
+				abbrev, ok = word[:len(word)-len(p.pattern)]+p.abbrev, true
+				break
+			}
+		}
+	}
+
+	// an abbreviation that is not shorter is no abbreviation, e.g., "model."
+	// for "model"
+	if !ok || abbrev == notAbbreviated || len(strings.TrimSuffix(abbrev, ".")) >= len(word) {
+		return "", false
+	}
+
+	return abbrev, true
+}
+
+// iso4Omitted are the articles, prepositions, and conjunctions that ISO 4
+// leaves out.
+var iso4Omitted = map[string]struct{}{
+	"a":    {},
+	"an":   {},
+	"and":  {},
+	"at":   {},
+	"by":   {},
+	"for":  {},
+	"from": {},
+	"in":   {},
+	"of":   {},
+	"on":   {},
+	"the":  {},
+	"to":   {},
+	"with": {},
+	"&":    {},
+	`\&`:   {},
+	"der":  {},
+	"die":  {},
+	"das":  {},
+	"des":  {},
+	"für":  {},
+	"und":  {},
+	"de":   {},
+	"du":   {},
+	"et":   {},
+	"la":   {},
+	"le":   {},
+	"les":  {},
+}
+
+// AbbreviateISO4 abbreviates the booktitle and journal according to ISO 4,
+// using the List of Title Word Abbreviations in data/ltwa.txt: words are
+// abbreviated, articles, prepositions, and conjunctions are left out, and
+// titles that consist of a single word (e.g., "Nature") are not
+// abbreviated. Words in braces and acronyms are kept as they are. Venues
+// in the abbreviation lists take precedence.
+func AbbreviateISO4(lists AbbreviationLists) func(e Element) Element {
+	return func(e Element) Element {
+		for _, key := range []string{"booktitle", "journal"} {
+			val, ok := e.Tags[key]
+			if !ok || lists.abbreviateFromList(e, key) {
+				continue
+			}
+
+			e.Tags[key] = mapStrings(val, abbreviateISO4)
+		}
+
+		return e
+	}
+}
+
+func abbreviateISO4(s string) string {
+	var words []string
+
+	for i, w := range splitNameWords(s, false) {
+		// "A" is a section, as in "Physical Review A", unless it starts the title
+		t := strings.TrimRight(w, ",:;")
+		if _, ok := iso4Omitted[strings.ToLower(t)]; ok && (i == 0 || t != "A") {
+			continue
+		}
+
+		words = append(words, w)
+	}
+
+	switch len(words) {
+	case 0:
+		return s
+	case 1:
+		return words[0]
+	}
+
+	for i, w := range words {
+		words[i] = abbreviateISO4Word(w)
+	}
+
+	return strings.Join(words, " ")
+}
+
+// abbreviateISO4Word abbreviates a single word, keeping its capitalization
+// and punctuation. The parts of hyphenated words are abbreviated separately.
+func abbreviateISO4Word(w string) string {
+	if strings.ContainsAny(w, `{}\$`) || strings.IndexFunc(w, unicode.IsDigit) >= 0 || isAllCaps(w) {
+		return w
+	}
+
+	core := strings.TrimRight(w, ",.;:")
+	punct := w[len(core):]
+
+	if strings.Contains(core, "-") {
+		parts := strings.Split(core, "-")
+		for i, p := range parts {
+			parts[i] = abbreviateISO4Word(p)
+		}
+		return strings.Join(parts, "-") + punct
+	}
+
+	abbrev, ok := titleWords.abbreviate(strings.ToLower(core))
+	if !ok {
+		return w
+	}
+
+	if r := []rune(core); len(r) > 0 && unicode.IsUpper(r[0]) {
+		a := []rune(abbrev)
+		a[0] = unicode.ToUpper(a[0])
+		abbrev = string(a)
+	}
+
+	if strings.HasSuffix(abbrev, ".") {
+		punct = strings.TrimPrefix(punct, ".")
+	}
+
+	return abbrev + punct
+}
@@ -0,0 +1,136 @@
+package bibtex
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// monthMacros are the standard BibTeX month macros.
+var monthMacros = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+
+// monthNames are month names in English, German, French, Spanish, Italian,
+// Dutch, and Portuguese, without accents. Abbreviations are matched as
+// prefixes of these.
+var monthNames = [][]string{
+	{"january", "januar", "janvier", "enero", "gennaio", "januari", "janeiro"},
+	{"february", "februar", "fevrier", "febrero", "febbraio", "februari", "fevereiro"},
+	{"march", "marz", "maerz", "mars", "marzo", "maart", "marco"},
+	{"april", "avril", "abril", "aprile"},
+	{"may", "mai", "mayo", "maggio", "mei", "maio"},
+	{"june", "juni", "juin", "junio", "giugno", "junho"},
+	{"july", "juli", "juillet", "julio", "luglio", "julho"},
+	{"august", "aout", "agosto", "augustus"},
+	{"september", "septembre", "septiembre", "settembre", "setembro"},
+	{"october", "oktober", "octobre", "octubre", "ottobre", "outubro"},
+	{"november", "novembre", "noviembre", "novembro"},
+	{"december", "dezember", "decembre", "diciembre", "dicembre", "dezembro"},
+}
+
+var monthAccents = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ó", "o", "ô", "o", "ö", "o",
+	"ú", "u", "û", "u", "ü", "u", "ç", "c",
+)
+
+// NormalizeMonth rewrites the month field to the form the style expects:
+// the bare macro ("sep") for BibTeX styles and the month number for biblatex.
+// Month names and abbreviations in several languages, numbers, and ranges
+// ("Jun.--Jul.") are recognized. Values we cannot make sense of are kept and
+// reported.
+func NormalizeMonth(style string) func(e Element) Element {
+	return func(e Element) Element {
+		val, ok := e.Tags["month"]
+		if !ok || val == "" {
+			return e
+		}
+
+		months, ok := parseMonths(val)
+		if !ok {
+			warnf(e, "cannot parse month %s", val)
+			return e
+		}
+
+		e.Tags["month"] = formatMonths(e, style, months)
+
+		return e
+	}
+}
+
+// formatMonths renders one month or a range of months for the style.
+func formatMonths(e Element, style string, months []int) string {
+	if style == StyleBibLaTeX {
+		if len(months) > 1 {
+			warnf(e, "biblatex does not support month ranges, using %s only", monthMacros[months[0]-1])
+		}
+		return strconv.Itoa(months[0])
+	}
+
+	out := make([]string, len(months))
+	for i, m := range months {
+		out[i] = monthMacros[m-1]
+	}
+
+	return strings.Join(out, ` # "--" # `)
+}
+
+var monthSeparator = regexp.MustCompile(`\s*(--?|–|—|/|\s)\s*`)
+
+// parseMonths parses a month value into one or two month numbers (1-12).
+func parseMonths(val string) ([]int, bool) {
+	// get rid of delimiters, concatenation, escapes, and abbreviation dots
+	s := strings.ToLower(val)
+	s = strings.NewReplacer(`"`, "", "{", "", "}", "", "#", " ", `\`, "", ".", " ", "~", " ").Replace(s)
+	s = monthAccents.Replace(strings.TrimSpace(s))
+
+	var months []int
+	for _, p := range monthSeparator.Split(s, -1) {
+		if p == "" {
+			continue
+		}
+
+		m, ok := parseMonth(p)
+		if !ok {
+			return nil, false
+		}
+		months = append(months, m)
+	}
+
+	if len(months) == 0 || len(months) > 2 {
+		return nil, false
+	}
+
+	if len(months) == 2 && months[0] == months[1] {
+		months = months[:1]
+	}
+
+	return months, true
+}
+
+// parseMonth parses a single month name, abbreviation, or number.
+func parseMonth(s string) (int, bool) {
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, n >= 1 && n <= 12
+	}
+
+	// abbreviations need at least three letters to be unambiguous
+	if len(s) < 3 {
+		return 0, false
+	}
+
+	found := 0
+	for i, names := range monthNames {
+		for _, name := range names {
+			if strings.HasPrefix(name, s) {
+				if found != 0 && found != i+1 {
+					// e.g., "jui" could be "juin" or "juillet"
+					return 0, false
+				}
+				found = i + 1
+			}
+		}
+	}
+
+	return found, found != 0
+}
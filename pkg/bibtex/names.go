@@ -0,0 +1,309 @@
+package bibtex
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Forms that NormalizeNames can write names in.
+const (
+	NamesLastFirst = "last-first"
+	NamesFirstLast = "first-last"
+)
+
+// nameFields are the fields that hold lists of names.
+var nameFields = []string{"author", "editor"}
+
+var (
+	nameInitials       = regexp.MustCompile(`^(\p{Lu}\.)+$`)
+	nameInitial        = regexp.MustCompile(`^\p{Lu}$`)
+	nameHyphenInitials = regexp.MustCompile(`^\p{Lu}\.?-\p{Lu}\.?$`)
+)
+
+// name is a personal name split into the four parts that BibTeX knows.
+type name struct {
+	first []string
+	von   []string
+	last  []string
+	jr    []string
+}
+
+// NormalizeNames rewrites the author and editor lists so that all names
+// have the same form ("Last, First" or "First Last"), initials are written
+// as "J. R.", names in all caps are capitalized properly, and empty names
+// from duplicated or trailing "and"s are removed. Names in braces (e.g.,
+// corporate authors) are kept as they are.
+func NormalizeNames(form string) func(e Element) Element {
+	return func(e Element) Element {
+		for _, key := range nameFields {
+			val, ok := e.Tags[key]
+			if !ok {
+				continue
+			}
+
+			s, ok := unquote(val)
+			if !ok {
+				continue
+			}
+
+			names := splitNames(s)
+			for i, n := range names {
+				if n == "others" {
+					continue
+				}
+
+				names[i] = parseName(n).format(form)
+			}
+
+			e.Tags[key] = quote(strings.Join(names, " and "))
+		}
+
+		return e
+	}
+}
+
+// splitNames splits a list of names at the "and"s that are not in braces.
+// Empty names are dropped.
+func splitNames(s string) []string {
+	var names []string
+	var current []string
+
+	for _, w := range splitNameWords(s, false) {
+		if strings.EqualFold(w, "and") {
+			if len(current) > 0 {
+				names = append(names, strings.Join(current, " "))
+			}
+			current = nil
+			continue
+		}
+
+		current = append(current, w)
+	}
+
+	if len(current) > 0 {
+		names = append(names, strings.Join(current, " "))
+	}
+
+	return names
+}
+
+// splitNameWords splits s at whitespace (and ties) outside of braces. If
+// commas is set, commas outside of braces are returned as separate words.
+func splitNameWords(s string, commas bool) []string {
+	var words []string
+
+	depth := 0
+	start := 0
+
+	for i, r := range s {
+		switch {
+		case r == '{':
+			depth++
+		case r == '}':
+			depth--
+		case depth == 0 && (unicode.IsSpace(r) || r == '~' || (commas && r == ',')):
+			if i > start {
+				words = append(words, s[start:i])
+			}
+			if r == ',' {
+				words = append(words, ",")
+			}
+			start = i + 1
+		}
+	}
+
+	if start < len(s) {
+		words = append(words, s[start:])
+	}
+
+	return words
+}
+
+// parseName splits a name the way BibTeX does: "First von Last",
+// "von Last, First", or "von Last, Jr, First".
+func parseName(s string) name {
+	var parts [][]string
+	var current []string
+
+	for _, w := range splitNameWords(s, true) {
+		if w == "," {
+			parts = append(parts, current)
+			current = nil
+			continue
+		}
+		current = append(current, w)
+	}
+	parts = append(parts, current)
+
+	var n name
+
+	switch len(parts) {
+	case 1:
+		// First von Last
+		words := parts[0]
+		if len(words) == 0 {
+			break
+		}
+
+		vonStart, vonEnd := -1, -1
+		for i, w := range words[:len(words)-1] {
+			if isVonWord(w) {
+				if vonStart < 0 {
+					vonStart = i
+				}
+				vonEnd = i + 1
+			}
+		}
+
+		if vonStart < 0 {
+			n.first = words[:len(words)-1]
+			n.last = words[len(words)-1:]
+			break
+		}
+
+		n.first = words[:vonStart]
+		n.von = words[vonStart:vonEnd]
+		n.last = words[vonEnd:]
+	default:
+		// von Last, [Jr,] First
+		n.von, n.last = splitVonLast(parts[0])
+		if len(parts) > 2 {
+			n.jr = parts[1]
+		}
+		n.first = parts[len(parts)-1]
+	}
+
+	n.normalize()
+
+	return n
+}
+
+// splitVonLast splits "von Last": the von part are all words up to the last
+// lowercase one, but the last word always belongs to the last name.
+func splitVonLast(words []string) (von []string, last []string) {
+	end := 0
+	for i, w := range words {
+		if i < len(words)-1 && isVonWord(w) {
+			end = i + 1
+		}
+	}
+
+	return words[:end], words[end:]
+}
+
+// isVonWord reports whether a word starts with a lowercase letter outside
+// of braces (special characters such as {\"u} count as letters).
+func isVonWord(w string) bool {
+	depth := 0
+	for i, r := range w {
+		switch {
+		case r == '{':
+			// a brace group that is not a special character protects the word
+			if depth == 0 && !strings.HasPrefix(w[i:], `{\`) {
+				return false
+			}
+			depth++
+		case r == '}':
+			depth--
+		case unicode.IsLetter(r):
+			return unicode.IsLower(r)
+		}
+	}
+
+	return false
+}
+
+// normalize writes initials consistently and fixes names in all caps.
+func (n *name) normalize() {
+	allCaps := true
+	for _, p := range [][]string{n.first, n.von, n.last} {
+		for _, w := range p {
+			if strings.IndexFunc(w, unicode.IsLower) >= 0 {
+				allCaps = false
+			}
+		}
+	}
+
+	// a single word (e.g., a corporate author) is left alone
+	single := len(n.first)+len(n.von)+len(n.last) == 1
+
+	for _, p := range [][]string{n.first, n.last} {
+		for i, w := range p {
+			if single || nameInitials.MatchString(w) || nameHyphenInitials.MatchString(w) {
+				continue
+			}
+			if isAllCaps(w) && (allCaps || len([]rune(w)) > 2) {
+				p[i] = capitalizeName(w)
+			}
+		}
+	}
+
+	var first []string
+	for _, w := range n.first {
+		switch {
+		case nameInitial.MatchString(w):
+			first = append(first, w+".")
+		case nameInitials.MatchString(w):
+			first = append(first, strings.Fields(strings.ReplaceAll(w, ".", ". "))...)
+		case nameHyphenInitials.MatchString(w):
+			c := strings.Split(w, "-")
+			first = append(first, strings.TrimSuffix(c[0], ".")+".-"+strings.TrimSuffix(c[1], ".")+".")
+		default:
+			first = append(first, w)
+		}
+	}
+	n.first = first
+}
+
+// isAllCaps reports whether a word has at least two letters, none of which
+// is lowercase. Words with braces are left alone.
+func isAllCaps(w string) bool {
+	if strings.ContainsAny(w, `{}\`) {
+		return false
+	}
+
+	letters := 0
+	for _, r := range w {
+		if unicode.IsLower(r) {
+			return false
+		}
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+
+	return letters > 1
+}
+
+// capitalizeName turns "SMITH" into "Smith", "JEAN-PAUL" into "Jean-Paul",
+// and "O'BRIEN" into "O'Brien".
+func capitalizeName(w string) string {
+	r := []rune(strings.ToLower(w))
+	for i := range r {
+		if i == 0 || r[i-1] == '-' || r[i-1] == '\'' {
+			r[i] = unicode.ToUpper(r[i])
+		}
+	}
+
+	return string(r)
+}
+
+// format writes the name in the given form. Names with a Jr part always use
+// the comma form since BibTeX cannot parse them otherwise.
+func (n name) format(form string) string {
+	vonLast := strings.Join(append(append([]string{}, n.von...), n.last...), " ")
+	first := strings.Join(n.first, " ")
+	jr := strings.Join(n.jr, " ")
+
+	switch {
+	case first == "" && jr == "":
+		return vonLast
+	case jr != "":
+		return vonLast + ", " + jr + ", " + first
+	case form == NamesFirstLast:
+		return first + " " + vonLast
+	default:
+		return vonLast + ", " + first
+	}
+}
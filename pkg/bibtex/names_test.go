@@ -0,0 +1,113 @@
+package bibtex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseName(t *testing.T) {
+	// the parts of the name as "first | von | last | jr"
+	tests := map[string]string{
+		"John Smith":               "John |  | Smith | ",
+		"Smith, John":              "John |  | Smith | ",
+		"J.~Smith":                 "J. |  | Smith | ",
+		"John Ronald Smith":        "John Ronald |  | Smith | ",
+		"Ludwig van Beethoven":     "Ludwig | van | Beethoven | ",
+		"van Beethoven, Ludwig":    "Ludwig | van | Beethoven | ",
+		"Jean de la Fontaine":      "Jean | de la | Fontaine | ",
+		"de la Fontaine, Jean":     "Jean | de la | Fontaine | ",
+		"Smith, Jr., John":         "John |  | Smith | Jr.",
+		"John {de la Cruz}":        "John |  | {de la Cruz} | ",
+		"{\\'E}mile Zola":          "{\\'E}mile |  | Zola | ",
+		"{NASA}":                   " |  | {NASA} | ",
+		"Smith":                    " |  | Smith | ",
+		"SMITH, JOHN":              "John |  | Smith | ",
+		"O'BRIEN, JEAN-PAUL":       "Jean-Paul |  | O'Brien | ",
+		"Smith, J.R.":              "J. R. |  | Smith | ",
+		"Smith, J R":               "J. R. |  | Smith | ",
+		"Smith, J.-P.":             "J.-P. |  | Smith | ",
+		"Smith, JP":                "JP |  | Smith | ",
+		"MCDONALD, Jane":           "Jane |  | Mcdonald | ",
+		"Xu Li":                    "Xu |  | Li | ",
+		"LI, XU":                   "Xu |  | Li | ",
+		"Anna {\\\"O}berg-Larsson": "Anna |  | {\\\"O}berg-Larsson | ",
+	}
+
+	for in, want := range tests {
+		n := parseName(in)
+
+		got := strings.Join([]string{
+			strings.Join(n.first, " "),
+			strings.Join(n.von, " "),
+			strings.Join(n.last, " "),
+			strings.Join(n.jr, " "),
+		}, " | ")
+
+		if got != want {
+			t.Errorf("parseName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNormalizeNames(t *testing.T) {
+	tests := []struct {
+		in        string
+		lastFirst string
+		firstLast string
+	}{
+		{
+			`"Smith, J. and John Doe and J.~R.~Miller"`,
+			`"Smith, J. and Doe, John and Miller, J. R."`,
+			`"J. Smith and John Doe and J. R. Miller"`,
+		},
+		{
+			`"SMITH, JOHN and van Beethoven, Ludwig"`,
+			`"Smith, John and van Beethoven, Ludwig"`,
+			`"John Smith and Ludwig van Beethoven"`,
+		},
+		{
+			// BibTeX cannot parse "John Smith Jr." so the comma form stays
+			`"Smith, Jr., John"`,
+			`"Smith, Jr., John"`,
+			`"Smith, Jr., John"`,
+		},
+		{
+			// duplicated and trailing "and"s
+			`"Smith, John and and Jane Doe and"`,
+			`"Smith, John and Doe, Jane"`,
+			`"John Smith and Jane Doe"`,
+		},
+		{
+			`"Smith, John AND Jane Doe and others"`,
+			`"Smith, John and Doe, Jane and others"`,
+			`"John Smith and Jane Doe and others"`,
+		},
+		{
+			// "and" in braces does not separate names
+			`"{Barnes and Noble} and Jane Doe"`,
+			`"{Barnes and Noble} and Doe, Jane"`,
+			`"{Barnes and Noble} and Jane Doe"`,
+		},
+		{
+			`{SMITH, J.}`,
+			`"Smith, J."`,
+			`"J. Smith"`,
+		},
+	}
+
+	for _, form := range []string{NamesLastFirst, NamesFirstLast} {
+		for _, tt := range tests {
+			want := tt.lastFirst
+			if form == NamesFirstLast {
+				want = tt.firstLast
+			}
+
+			e := Element{ID: "test", Tags: map[string]string{"author": tt.in, "editor": tt.in}}
+			e = NormalizeNames(form)(e)
+
+			if e.Tags["author"] != want || e.Tags["editor"] != want {
+				t.Errorf("NormalizeNames(%q) of %s = %s and %s, want %s", form, tt.in, e.Tags["author"], e.Tags["editor"], want)
+			}
+		}
+	}
+}
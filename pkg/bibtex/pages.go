@@ -0,0 +1,136 @@
+package bibtex
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	pagesPrefix        = regexp.MustCompile(`^(?i)(pp?\.|pages?\s)\s*`)
+	pagesRangeSplitter = regexp.MustCompile(`\s*(-+|–|—|\\textendash\s*|\\textemdash\s*|\{--\})\s*`)
+	pagesToken         = regexp.MustCompile(`^([A-Za-z]*)(\d+)(?::(\d+))?([A-Za-z]*)$`)
+	pagesRoman         = regexp.MustCompile(`^(?i)[ivxlcdm]+$`)
+)
+
+// page is a single page "number", e.g., "12", "e1234", "S12", "123:4"
+// (an ACM article number with a page), or "xiv".
+type page struct {
+	prefix  string
+	number  int
+	article int
+	sub     int
+	suffix  string
+	roman   bool
+}
+
+func parsePage(s string) (page, bool) {
+	if pagesRoman.MatchString(s) {
+		return page{number: romanToInt(s), roman: true}, true
+	}
+
+	m := pagesToken.FindStringSubmatch(s)
+	if m == nil {
+		return page{}, false
+	}
+
+	p := page{prefix: m[1], suffix: m[4]}
+	p.number, _ = strconv.Atoi(m[2])
+
+	if m[3] != "" {
+		// ACM style "<article>:<page>"
+		p.article = p.number
+		p.number, _ = strconv.Atoi(m[3])
+	}
+
+	return p, true
+}
+
+// CleanPages normalizes the pages field: page ranges get an en-dash ("--")
+// between their first and last page, "pp." prefixes are removed, and
+// single pages, article numbers ("e1234", "123:1--123:25"), prefixed pages
+// ("S12--S18"), and roman numerals are kept as they are. Reversed and
+// otherwise suspicious ranges are reported.
+func CleanPages(e Element) Element {
+	val, ok := e.Tags["pages"]
+	if !ok {
+		return e
+	}
+
+	s, ok := unquote(val)
+	if !ok {
+		// a bare number is a single page and fine as it is
+		return e
+	}
+
+	s = pagesPrefix.ReplaceAllString(strings.TrimSpace(s), "")
+
+	// there may be a list of ranges, e.g., "1--5, 7--9"
+	ranges := strings.Split(s, ",")
+	for i, r := range ranges {
+		ranges[i] = cleanPageRange(e, strings.TrimSpace(r))
+	}
+
+	e.Tags["pages"] = quote(strings.Join(ranges, ", "))
+
+	return e
+}
+
+// cleanPageRange cleans a single page or page range.
+func cleanPageRange(e Element, r string) string {
+	bounds := pagesRangeSplitter.Split(r, -1)
+
+	switch len(bounds) {
+	case 1:
+		if _, ok := parsePage(r); !ok {
+			warnf(e, "unusual page %q", r)
+		}
+		return r
+	case 2:
+	default:
+		warnf(e, "cannot parse page range %q", r)
+		return r
+	}
+
+	first, ok1 := parsePage(bounds[0])
+	last, ok2 := parsePage(bounds[1])
+
+	if !ok1 || !ok2 {
+		warnf(e, "cannot parse page range %q", r)
+		return r
+	}
+
+	switch {
+	case first.roman != last.roman || first.prefix != last.prefix || first.suffix != last.suffix:
+		warnf(e, "page range %q mixes different kinds of pages", r)
+	case first.article != last.article:
+		warnf(e, "page range %q spans several article numbers", r)
+	case first.number == last.number:
+		// "12--12" is just page 12
+		return bounds[0]
+	case first.number > last.number && len(bounds[1]) < len(bounds[0]):
+		warnf(e, "page range %q looks abbreviated, should it be %s--%s%s?", r, bounds[0], bounds[0][:len(bounds[0])-len(bounds[1])], bounds[1])
+	case first.number > last.number:
+		warnf(e, "page range %q is reversed", r)
+	}
+
+	return bounds[0] + "--" + bounds[1]
+}
+
+// romanToInt converts a (lowercase or uppercase) roman numeral.
+func romanToInt(s string) int {
+	values := map[byte]int{'i': 1, 'v': 5, 'x': 10, 'l': 50, 'c': 100, 'd': 500, 'm': 1000}
+
+	s = strings.ToLower(s)
+	n := 0
+	for i := 0; i < len(s); i++ {
+		v := values[s[i]]
+		if i+1 < len(s) && values[s[i+1]] > v {
+			n -= v
+		} else {
+			n += v
+		}
+	}
+
+	return n
+}
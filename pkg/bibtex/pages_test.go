@@ -0,0 +1,91 @@
+package bibtex
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+// captureWarnings runs f and returns everything it reported with warnf.
+func captureWarnings(f func()) string {
+	var buf bytes.Buffer
+
+	out, flags := log.Writer(), log.Flags()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(out)
+		log.SetFlags(flags)
+	}()
+
+	f()
+
+	return buf.String()
+}
+
+func TestCleanPages(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		// warn is part of the expected warning, empty if there should be none
+		warn string
+	}{
+		// ranges get an en-dash, whatever they had before
+		{`"12-34"`, `"12--34"`, ""},
+		{`"12 --- 34"`, `"12--34"`, ""},
+		{`"12–34"`, `"12--34"`, ""},
+		{`"12\textendash 34"`, `"12--34"`, ""},
+		{`"12{--}34"`, `"12--34"`, ""},
+		{`"pp. 12-34"`, `"12--34"`, ""},
+		{`"pages 12-34"`, `"12--34"`, ""},
+		{`"12--12"`, `"12"`, ""},
+		{`"1-5, 7-9"`, `"1--5, 7--9"`, ""},
+
+		// single pages and article numbers are not ranges
+		{`"e1234"`, `"e1234"`, ""},
+		{`"p. 7"`, `"7"`, ""},
+		{`"123:1-123:25"`, `"123:1--123:25"`, ""},
+		{`"123:1--123:25"`, `"123:1--123:25"`, ""},
+		{`"S12-S18"`, `"S12--S18"`, ""},
+		{`"12a-14a"`, `"12a--14a"`, ""},
+		{`"xi-xiv"`, `"xi--xiv"`, ""},
+		{`"XIV"`, `"XIV"`, ""},
+		{`12`, `12`, ""},
+
+		// suspicious ranges are kept and reported
+		{`"34-12"`, `"34--12"`, "is reversed"},
+		{`"1234-56"`, `"1234--56"`, "should it be 1234--1256?"},
+		{`"S12-34"`, `"S12--34"`, "mixes different kinds of pages"},
+		{`"xii-5"`, `"xii--5"`, "mixes different kinds of pages"},
+		{`"1:1-2:5"`, `"1:1--2:5"`, "spans several article numbers"},
+		{`"1-2-3"`, `"1-2-3"`, "cannot parse page range"},
+		{`"12.5"`, `"12.5"`, "unusual page"},
+	}
+
+	for _, tt := range tests {
+		e := Element{ID: "test", Tags: map[string]string{"pages": tt.in}}
+
+		warned := captureWarnings(func() { e = CleanPages(e) })
+
+		if got := e.Tags["pages"]; got != tt.want {
+			t.Errorf("CleanPages(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+
+		if tt.warn == "" && warned != "" {
+			t.Errorf("CleanPages(%s) warned %q", tt.in, warned)
+		}
+
+		if tt.warn != "" && !strings.Contains(warned, tt.warn) {
+			t.Errorf("CleanPages(%s) warned %q, want a warning with %q", tt.in, warned, tt.warn)
+		}
+	}
+}
+
+func TestRomanToInt(t *testing.T) {
+	for in, want := range map[string]int{"i": 1, "iv": 4, "ix": 9, "xiv": 14, "XL": 40, "mcmxcix": 1999} {
+		if got := romanToInt(in); got != want {
+			t.Errorf("romanToInt(%q) = %d, want %d", in, got, want)
+		}
+	}
+}
@@ -5,31 +5,45 @@
 package bibtex
 
 import (
+	"fmt"
+	"log"
 	"regexp"
 	"strings"
+	"unicode"
 )
 
-// CleanPages removes single dashes from page numbers and replaces
-// them with an em-dash ("--").
-func CleanPages(e Element) Element {
-	for key, val := range e.Tags {
-		if key != "pages" {
-			continue
-		}
+// Styles that plugins can be configured for, these match the --defaults of
+// bibclean.
+const (
+	StyleIEEE     = "ieee"
+	StyleACM      = "acm"
+	StyleBibLaTeX = "biblatex"
+)
 
-		// check if there is something that has two numbers seperated by something
-		r := regexp.MustCompile(`"\d+[^\d]+\d+"`)
-		if !r.MatchString(val) {
-			// if not, it's probably something weird like Elsevier
-			continue
+// CheckRequired records the fields that an element is missing in
+// Element.Missing. required maps entry types to the fields that they must
+// have, where alternatives are separated by "|" (e.g., "author|editor" for
+// books). Run it after all other plugins.
+func CheckRequired(required map[string][]string) func(e Element) Element {
+	return func(e Element) Element {
+		e.Missing = nil
+
+		for _, field := range required[e.Type] {
+			found := false
+			for _, alt := range strings.Split(field, "|") {
+				if val, ok := e.Tags[alt]; ok && val != "" && val != `""` && val != "{}" {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				e.Missing = append(e.Missing, field)
+			}
 		}
 
-		// replace the non-numbers with an em-dash
-		r = regexp.MustCompile(`[^\d"]+`)
-		e.Tags[key] = r.ReplaceAllString(val, "--")
+		return e
 	}
-
-	return e
 }
 
 // CleanCurly removes the useless escaped curly braces from USENIX
@@ -57,17 +71,13 @@ func CleanCurly(e Element) Element {
 // Also, we need to change umlaut escapes: \"{a} does not work, it should be {\"a} so the quotes are in braces.
 func CleanQuotationMarks(e Element) Element {
 	for key, val := range e.Tags {
+		// months are taken care of by NormalizeMonth
 		if key == "month" {
-			r := regexp.MustCompile(`[^a-z]`)
-			e.Tags[key] = r.ReplaceAllString(val, "")
 			continue
 		}
 
-		// this is probably a bad idea
-		// in case the number of volume does have a letter in it for some reason
-		if key == "year" || key == "volume" {
-			r := regexp.MustCompile(`[^\d]`)
-			e.Tags[key] = r.ReplaceAllString(val, "")
+		// numbers are taken care of by CleanNumbers
+		if _, ok := numberFields[key]; ok {
 			continue
 		}
 
@@ -87,166 +97,290 @@ func CleanQuotationMarks(e Element) Element {
 	return e
 }
 
-// AddProcOf adds "Proceedings of the" to the start of the booktitle if it is missing.
-func AddProcOf(e Element) Element {
-	// only for inproceedings
-	if e.Type != "inproceedings" {
-		return e
-	}
+var (
+	procOfIn     = regexp.MustCompile(`^(?i)in:?\s+`)
+	procOfThe    = regexp.MustCompile(`^(?i)the\s+`)
+	procOfEvent  = regexp.MustCompile(`^(\S+( \S+)? ('\d{2}|\d{4})|\S+)$`)
+	procOfInside = regexp.MustCompile(`(?i)\bproc(eedings|eeding|s?\.)`)
+)
 
-	for key, val := range e.Tags {
-		if key != "booktitle" {
+// procOfPrefix matches the ways a booktitle can already start with
+// "Proceedings of the", including the abbreviation from ieeeTitleShortforms.
+var procOfPrefix = regexp.MustCompile(`^(?i)(proceedings|proceeding|procs\.|` +
+	regexp.QuoteMeta(strings.TrimSpace((*ieeeTitleShortforms)["Proceedings "])) +
+	`)\s*(of\s+)?(the\s+)?`)
+
+// numberFields are fields that usually hold a number.
+var numberFields = map[string]struct{}{
+	"year":   {},
+	"volume": {},
+	"number": {},
+	"issue":  {},
+}
+
+var (
+	numberDigits      = regexp.MustCompile(`^\d+$`)
+	numberRange       = regexp.MustCompile(`^(\w+)\s*(-+|–|—)\s*(\w+)$`)
+	numberPlaceholder = regexp.MustCompile(`^(?i)([?\-–—.*_ ]*|n/?a|none|unknown|tbd)$`)
+	numberLabel       = regexp.MustCompile(`^(?i)(vol|volume|no|nr|num|number|iss|issue)\.?[\s~]*#?\s*(\d)|^#\s*(\d)`)
+	numberYear        = regexp.MustCompile(`^(\d{4}[a-z]?|\d{4}--\d{4}|(?i)in press|forthcoming|to appear|submitted|n\.\s?d\.)$`)
+)
+
+// CleanNumbers validates year, volume, number, and issue. Labels that the
+// style prints itself (e.g., "Vol. 3" or "No. 2") are removed, plain numbers
+// are written without quotes, ranges get an en-dash ("1--2"), and other
+// values that are legitimate (e.g., "12A", "Suppl. 2", or "2021a") are kept
+// in quotes. Placeholders such as "??" or "n/a" are removed and, like other
+// values that look broken, reported.
+func CleanNumbers(e Element) Element {
+	for key := range numberFields {
+		val, ok := e.Tags[key]
+		if !ok {
+			continue
+		}
+
+		s, ok := unquote(val)
+		if !ok {
+			s = val
+		}
+		// braces don't protect anything here
+		s = strings.TrimSpace(strings.Trim(strings.TrimSpace(s), "{}"))
+
+		if numberPlaceholder.MatchString(s) {
+			warnf(e, "removing placeholder %s = %s", key, val)
+			delete(e.Tags, key)
 			continue
 		}
 
-		// check if the booktitle is empty or MISSING
-		if val == "" || val == "MISSING" {
-			break
+		// "Vol. 3" would be printed as "vol. Vol. 3"
+		if key != "year" {
+			s = numberLabel.ReplaceAllString(s, "$2$3")
 		}
 
-		// check if the booktitle starts with "Proceedings of the"
-		r := regexp.MustCompile(`^"Proceedings of the`)
-		if r.MatchString(val) {
+		// only plain digits are valid bare numbers, "+3" or "-1" are not
+		if numberDigits.MatchString(s) {
+			if key == "year" && (len(s) != 4 || s < "1000") {
+				warnf(e, "unusual year %s", s)
+			}
+
+			e.Tags[key] = s
 			continue
 		}
 
-		e.Tags[key] = "\"Proceedings of the " + val[1:]
-		break
+		if m := numberRange.FindStringSubmatch(s); m != nil {
+			s = m[1] + "--" + m[3]
+		}
+
+		switch {
+		case key == "year":
+			if !numberYear.MatchString(s) {
+				warnf(e, "unusual year %s", s)
+			}
+		case strings.IndexFunc(s, unicode.IsDigit) < 0 && !pagesRoman.MatchString(s):
+			warnf(e, "%s %s has no number", key, s)
+		}
+
+		e.Tags[key] = quote(s)
 	}
 
 	return e
 }
 
-// CleanDOI checks doi and url fields and sets the other one if it is missing.
-func CleanDOI(e Element) Element {
-	// check if doi is missing
-	if doi, ok := e.Tags["doi"]; !ok || doi == "" {
-		// check if url is a doi
-		if _, ok = e.Tags["url"]; !ok {
-			// no doi, no url
+// AddProcOf adds "Proceedings of the" to the start of the booktitle if it is
+// missing. Booktitles that already start with "In", "Proc.", or "Proceedings
+// of" are rewritten to the same prefix (without adding "the" if it had none,
+// as in "Proc. of Middleware"), booktitles that mention proceedings
+// somewhere else are left alone, and event names such as "Middleware '21" or
+// "INFOCOM" do not get an article. ACM references do not want the prefix, so
+// for ACM we only remove a leading "In" (that the style adds itself).
+func AddProcOf(style string) func(e Element) Element {
+	return func(e Element) Element {
+		// only for inproceedings
+		if e.Type != "inproceedings" {
 			return e
 		}
 
-		r := regexp.MustCompile(`^"https?://(dx\.)?doi\.org/`)
-		if r.MatchString(e.Tags["url"]) {
-			// remove the doi.org prefix
-			e.Tags["doi"] = "\"" + r.ReplaceAllString(e.Tags["url"], "")
+		val, ok := e.Tags["booktitle"]
+		if !ok {
+			return e
 		}
-		return e
-	}
 
-	// check if url is missing
-	if url, ok := e.Tags["url"]; !ok || url == "" {
-		// check if doi is a doi!
-		if _, ok = e.Tags["doi"]; !ok {
-			// no doi, no url
+		s, ok := unquote(val)
+		if !ok {
 			return e
 		}
 
-		// assuming dois are characterised by having a slash in them
-		r := regexp.MustCompile(`^.*/.*$`)
-		if r.MatchString(e.Tags["doi"]) {
-			e.Tags["url"] = "\"https://doi.org/" + e.Tags["doi"][1:]
+		s = procOfIn.ReplaceAllString(strings.TrimSpace(s), "")
+		if style == StyleACM {
+			e.Tags["booktitle"] = quote(s)
+			return e
 		}
-	}
-
-	return e
-}
 
-// AddPublisherLocation adds the location of the publisher to the
-// entry. They're all based in New York for some reason.
-func AddPublisherAddress(e Element) Element {
-	// check if address exists but is empty
-	addresses := map[string]string{
-		"ACM":                                 "New York, NY, USA",
-		"Association for Computing Machinery": "New York, NY, USA",
-		"IEEE":                                "New York, NY, USA",
-		"USENIX":                              "Berkeley, CA, USA",
-		// Springer is difficult, they have multiple locations
-		// some are Berlin, Heidelberg, or Cham
-		//	"Springer":  "Berlin, Germany",
-		"Elsevier": "Amsterdam, The Netherlands",
-	}
+		// keep an article-less "Proceedings of Middleware" as it is
+		article := true
+		if m := procOfPrefix.FindStringSubmatch(s); m != nil {
+			article = m[2] == "" || m[3] != ""
+			s = s[len(m[0]):]
+		}
 
-	// if address is not a required field, we can't do anything
-	addressRequired := false
-	for _, req := range e.RequiredKeys.Required {
-		if req == "address" {
-			addressRequired = true
-			break
+		// "Proceedings" alone is all we know, so keep it
+		if s == "" {
+			return e
 		}
-	}
 
-	if !addressRequired {
-		return e
-	}
+		// something like "IEEE INFOCOM 2021 Conference Proceedings"
+		if procOfInside.MatchString(s) {
+			e.Tags["booktitle"] = quote(s)
+			return e
+		}
 
-	// if there is already an address, better not touch it
+		// don't add "the" to "the" or to the name of an event
+		s = procOfThe.ReplaceAllString(s, "")
+		if !article || procOfEvent.MatchString(s) {
+			e.Tags["booktitle"] = quote("Proceedings of " + s)
+			return e
+		}
 
-	if _, ok := e.Tags["address"]; !ok && e.Tags["address"] != "" {
-		return e
-	}
+		e.Tags["booktitle"] = quote("Proceedings of the " + s)
 
-	// if there is no publisher, we can't do anything
-	if _, ok := e.Tags["publisher"]; !ok {
 		return e
 	}
-
-	// check if the publisher is in the list
-	if addr, ok := addresses[e.Tags["publisher"][1:len(e.Tags["publisher"])-1]]; ok {
-		e.Tags["address"] = "\"" + addr + "\""
-	}
-
-	return e
 }
 
 // ShortenBooktitle replaces long conference names with approved short forms from IEEE.
-func ShortenBooktitle(e Element) Element {
-	for tag := range e.Tags {
-		if tag == "booktitle" || tag == "journal" {
-			for old, new := range *ieeeTitleShortforms {
-				//log.Printf("replacing %s with %s in %s", old, new, element.Tags[tag])
-				e.Tags[tag] = strings.Replace(e.Tags[tag], old, new, -1)
-				e.Tags[tag] = strings.Replace(e.Tags[tag], strings.ToLower(old), strings.ToLower(new), -1)
+// Only whole words are replaced, longer phrases first, and the short forms
+// keep the case of the words they replace. Text in braces is left alone.
+// Venues in the abbreviation lists take precedence.
+func ShortenBooktitle(lists AbbreviationLists) func(e Element) Element {
+	return func(e Element) Element {
+		for tag := range e.Tags {
+			if tag == "booktitle" || tag == "journal" {
+				if lists.abbreviateFromList(e, tag) {
+					continue
+				}
+
+				e.Tags[tag] = titleAbbreviations.shorten(e.Tags[tag])
 			}
 		}
-	}
 
-	return e
+		return e
+	}
 }
 
-// ShortenAuthors truncates the author list for more than three authors.
+// ShortenAuthors truncates author lists with more than two authors to the
+// first author.
 func ShortenAuthors(e Element) Element {
-	for key, val := range e.Tags {
-		if key != "author" {
-			continue
-		}
+	return TruncateNames(2, 1, false)(e)
+}
 
-		// check if there are more than three authors
-		r := regexp.MustCompile(`\sand.*and`)
-		if !r.MatchString(val) {
-			continue
+// TruncateNames cuts author lists with more than maxNames names down to the
+// first keep names followed by "others" (which styles print as "et al.").
+// If editors is set, editor lists are truncated as well. Names are split
+// only at "and"s outside of braces, so corporate authors stay intact.
+func TruncateNames(maxNames int, keep int, editors bool) func(e Element) Element {
+	return func(e Element) Element {
+		for _, key := range nameFields {
+			if key == "editor" && !editors {
+				continue
+			}
+
+			val, ok := e.Tags[key]
+			if !ok {
+				continue
+			}
+
+			s, ok := unquote(val)
+			if !ok {
+				continue
+			}
+
+			names := splitNames(s)
+			if len(names) > 0 && names[len(names)-1] == "others" {
+				names = names[:len(names)-1]
+			}
+
+			if len(names) <= maxNames {
+				continue
+			}
+
+			e.Tags[key] = quote(strings.Join(append(names[:keep:keep], "others"), " and "))
 		}
 
-		// replace everything after the first author with "et al."
-		r = regexp.MustCompile(`\sand.*$`)
-		e.Tags[key] = r.ReplaceAllString(val, " and others\"")
+		return e
 	}
+}
 
-	return e
+// ShortenAll replaces long words with approved short forms from IEEE, in the
+// same way as ShortenBooktitle. Use it together with TruncateNames to also
+// shorten the author list.
+func ShortenAll(lists AbbreviationLists) func(e Element) Element {
+	return func(e Element) Element {
+		for tag := range e.Tags {
+			if tag == "title" || tag == "booktitle" || tag == "journal" {
+				if tag != "title" && lists.abbreviateFromList(e, tag) {
+					continue
+				}
+
+				e.Tags[tag] = wordAbbreviations.shorten(e.Tags[tag])
+			}
+		}
+
+		return e
+	}
 }
 
-// ShortenAll replaces long words with approved short forms from IEEE.
-func ShortenAll(e Element) Element {
-	for tag := range e.Tags {
-		if tag == "title" || tag == "booktitle" || tag == "journal" {
-			for old, new := range *ieeeShortforms {
-				//log.Printf("replacing %s with %s in %s", old, new, element.Tags[tag])
-				e.Tags[tag] = strings.Replace(e.Tags[tag], old, new, -1)
-				e.Tags[tag] = strings.Replace(e.Tags[tag], strings.ToLower(old), strings.ToLower(new), -1)
+// unquote returns the text inside a value that is a single string delimited
+// by double quotes or curly braces. Macros, numbers, and concatenations are
+// not strings, so ok is false for them.
+func unquote(val string) (s string, ok bool) {
+	if len(val) < 2 {
+		return "", false
+	}
+
+	switch {
+	case val[0] == '"' && val[len(val)-1] == '"':
+		// a quotation mark outside of braces would end the string early
+		depth := 0
+		for i := 1; i < len(val)-1; i++ {
+			switch val[i] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			case '"':
+				if depth == 0 {
+					return "", false
+				}
 			}
 		}
+	case val[0] == '{' && val[len(val)-1] == '}':
+		// the first brace has to be closed by the last one
+		depth := 0
+		for i := 0; i < len(val)-1; i++ {
+			switch val[i] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			}
+			if depth == 0 {
+				return "", false
+			}
+		}
+	default:
+		return "", false
 	}
 
-	return ShortenAuthors(e)
+	return val[1 : len(val)-1], true
+}
+
+// quote wraps s in double quotes, the way CleanQuotationMarks leaves values.
+func quote(s string) string {
+	return "\"" + s + "\""
+}
+
+// warnf reports a problem with an element that we cannot (or should not)
+// fix automatically.
+func warnf(e Element, format string, a ...any) {
+	log.Printf("%s: %s", e.ID, fmt.Sprintf(format, a...))
 }
@@ -0,0 +1,86 @@
+package bibtex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAddProcOf(t *testing.T) {
+	tests := []struct {
+		style string
+		in    string
+		want  string
+	}{
+		{StyleIEEE, `"International Conference on Cloud Engineering"`, `"Proceedings of the International Conference on Cloud Engineering"`},
+		{StyleIEEE, `"In: The International Conference on Cloud Engineering"`, `"Proceedings of the International Conference on Cloud Engineering"`},
+		{StyleIEEE, `"Proc. of the International Conference on Cloud Engineering"`, `"Proceedings of the International Conference on Cloud Engineering"`},
+		{StyleIEEE, `"Proc. of Middleware"`, `"Proceedings of Middleware"`},
+		{StyleIEEE, `"Proceedings of Middleware '21"`, `"Proceedings of Middleware '21"`},
+		{StyleIEEE, `"Middleware '21"`, `"Proceedings of Middleware '21"`},
+		{StyleIEEE, `"INFOCOM"`, `"Proceedings of INFOCOM"`},
+		{StyleIEEE, `"IEEE INFOCOM 2021 Conference Proceedings"`, `"IEEE INFOCOM 2021 Conference Proceedings"`},
+
+		// there is nothing left to add the prefix to
+		{StyleIEEE, `"Proceedings"`, `"Proceedings"`},
+		{StyleIEEE, `"Proceedings of the"`, `"Proceedings of the"`},
+		{StyleIEEE, `""`, `""`},
+
+		{StyleACM, `"In International Conference on Cloud Engineering"`, `"International Conference on Cloud Engineering"`},
+	}
+
+	for _, tt := range tests {
+		e := Element{ID: "test", Type: "inproceedings", Tags: map[string]string{"booktitle": tt.in}}
+		if got := AddProcOf(tt.style)(e).Tags["booktitle"]; got != tt.want {
+			t.Errorf("AddProcOf(%q) of %s = %s, want %s", tt.style, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCleanNumbers(t *testing.T) {
+	tests := []struct {
+		key  string
+		in   string
+		want string
+		warn string
+	}{
+		{"volume", `"12"`, `12`, ""},
+		{"volume", `{12}`, `12`, ""},
+		{"volume", `"Vol. 3"`, `3`, ""},
+		{"volume", `"Volume 3"`, `3`, ""},
+		{"volume", `"vol.3"`, `3`, ""},
+		{"volume", `"Vol. 3--4"`, `"3--4"`, ""},
+		{"volume", `"Vol. 12A"`, `"12A"`, ""},
+		{"volume", `"Volume"`, `"Volume"`, "has no number"},
+		{"number", `"No. 2"`, `2`, ""},
+		{"number", `"no.~2"`, `2`, ""},
+		{"number", `"Nr. 2"`, `2`, ""},
+		{"number", `"#2"`, `2`, ""},
+		{"number", `"No #2"`, `2`, ""},
+		{"number", `"Nov. 2"`, `"Nov. 2"`, ""},
+		{"number", `"Suppl. 2"`, `"Suppl. 2"`, ""},
+		{"number", `"1-2"`, `"1--2"`, ""},
+		{"issue", `"Issue 4"`, `4`, ""},
+		{"issue", `"Special Issue"`, `"Special Issue"`, "has no number"},
+		{"issue", `"??"`, ``, "removing placeholder"},
+		{"year", `"2021"`, `2021`, ""},
+		{"year", `"No. 2021"`, `"No. 2021"`, "unusual year"},
+	}
+
+	for _, tt := range tests {
+		e := Element{ID: "test", Tags: map[string]string{tt.key: tt.in}}
+
+		warned := captureWarnings(func() { e = CleanNumbers(e) })
+
+		if got := e.Tags[tt.key]; got != tt.want {
+			t.Errorf("CleanNumbers(%s = %s) = %s, want %s", tt.key, tt.in, got, tt.want)
+		}
+
+		if tt.warn == "" && warned != "" {
+			t.Errorf("CleanNumbers(%s = %s) warned %q", tt.key, tt.in, warned)
+		}
+
+		if tt.warn != "" && !strings.Contains(warned, tt.warn) {
+			t.Errorf("CleanNumbers(%s = %s) warned %q, want a warning with %q", tt.key, tt.in, warned, tt.warn)
+		}
+	}
+}
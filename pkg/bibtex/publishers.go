@@ -0,0 +1,183 @@
+package bibtex
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed data/publishers.yaml
+var defaultPublishers []byte
+
+// Publisher is a publisher with the names it goes by and its address.
+type Publisher struct {
+	Name    string   `yaml:"name"`
+	Aliases []string `yaml:"aliases"`
+	// Styles are names that styles prefer over Name.
+	Styles map[string]string `yaml:"styles"`
+	// Address may be empty for publishers with many offices.
+	Address string `yaml:"address"`
+}
+
+// Publishers is a publisher table. Earlier publishers take precedence.
+type Publishers []*Publisher
+
+// DefaultPublishers returns the publisher table that comes with bibclean.
+func DefaultPublishers() Publishers {
+	p, err := LoadPublishers(defaultPublishers)
+	if err != nil {
+		panic(err)
+	}
+
+	return p
+}
+
+// LoadPublishers reads a publisher table from YAML or JSON.
+func LoadPublishers(data []byte) (Publishers, error) {
+	var publishers Publishers
+
+	if err := yaml.Unmarshal(data, &publishers); err != nil {
+		return nil, fmt.Errorf("could not read publishers: %w", err)
+	}
+
+	for _, p := range publishers {
+		if p.Name == "" {
+			return nil, fmt.Errorf("publisher without a name")
+		}
+	}
+
+	return publishers, nil
+}
+
+var publisherPunctuation = regexp.MustCompile(`[^\pL\pN]+`)
+
+// normalizePublisher prepares a publisher name for matching: lowercase, no
+// braces, escapes, or punctuation, "&" as "and", and single spaces.
+func normalizePublisher(s string) string {
+	s = strings.NewReplacer("{", "", "}", "", `\"`, "", `\`, "", "&", " and ").Replace(strings.ToLower(s))
+	s = strings.TrimSpace(publisherPunctuation.ReplaceAllString(s, " "))
+
+	return strings.TrimPrefix(s, "the ")
+}
+
+// Match finds a publisher by name or alias. A name that only starts with a
+// known name also matches, e.g., "ACM Press, New York" is "ACM Press". If
+// several publishers match, the longest name wins.
+func (publishers Publishers) Match(s string) *Publisher {
+	s = normalizePublisher(s)
+	if s == "" {
+		return nil
+	}
+
+	var best *Publisher
+	bestLen := 0
+
+	for _, p := range publishers {
+		for _, n := range append([]string{p.Name}, p.Aliases...) {
+			n = normalizePublisher(n)
+
+			if n == s {
+				return p
+			}
+
+			if strings.HasPrefix(s, n+" ") && len(n) > bestLen {
+				best = p
+				bestLen = len(n)
+			}
+		}
+	}
+
+	return best
+}
+
+// name returns the name that the style prefers for the publisher.
+func (p *Publisher) name(style string) string {
+	if n, ok := p.Styles[style]; ok {
+		return n
+	}
+
+	return p.Name
+}
+
+// NormalizePublisher rewrites the publisher to the name the style prefers,
+// e.g., "ACM Press" to "ACM" for IEEE and to "Association for Computing
+// Machinery" for ACM. Run it before AddPublisherAddress so that addresses
+// are looked up by the same names.
+func NormalizePublisher(publishers Publishers, style string) func(e Element) Element {
+	return func(e Element) Element {
+		val, ok := e.Tags["publisher"]
+		if !ok {
+			return e
+		}
+
+		s, ok := unquote(val)
+		if !ok {
+			return e
+		}
+
+		if p := publishers.Match(s); p != nil {
+			e.Tags["publisher"] = quote(p.name(style))
+		}
+
+		return e
+	}
+}
+
+// AddPublisherAddress adds the address of the publisher to the entry if
+// the style wants an address and the entry has none yet. An existing address
+// is never changed.
+func AddPublisherAddress(publishers Publishers) func(e Element) Element {
+	return func(e Element) Element {
+		// if address is not a required field, we can't do anything
+		if !wantsField(e, "address") {
+			return e
+		}
+
+		// if there is already an address, better not touch it
+		if hasField(e, "address") {
+			return e
+		}
+
+		// if there is no publisher, we can't do anything
+		publisher, ok := e.Tags["publisher"]
+		if !ok {
+			return e
+		}
+
+		if s, ok := unquote(publisher); ok {
+			publisher = s
+		}
+
+		if p := publishers.Match(publisher); p != nil && p.Address != "" {
+			e.Tags["address"] = quote(p.Address)
+		}
+
+		return e
+	}
+}
+
+// wantsField reports whether the style writes the field for the type of the
+// entry.
+func wantsField(e Element, key string) bool {
+	if e.RequiredKeys == nil {
+		return false
+	}
+
+	for _, req := range e.RequiredKeys.Required {
+		if req == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasField reports whether the entry has a value for the field that is not
+// empty.
+func hasField(e Element, key string) bool {
+	val, ok := e.Tags[key]
+	return ok && val != "" && val != `""` && val != "{}"
+}
@@ -0,0 +1,160 @@
+package bibtex
+
+import (
+	"regexp"
+	"strings"
+)
+
+// typeAliases are entry types that styles may not know and the type that
+// comes closest to them.
+var typeAliases = map[string]string{
+	"conference": "inproceedings",
+	"thesis":     "phdthesis",
+	"report":     "techreport",
+	"electronic": "online",
+	"www":        "online",
+	"webpage":    "online",
+	"inbook":     "incollection",
+	"booklet":    "misc",
+	"manual":     "misc",
+	"preprint":   "misc",
+}
+
+// fieldPairs are fields that mean the same thing for different entry types,
+// so they are renamed when the type changes.
+var fieldPairs = [][2]string{
+	{"journal", "booktitle"},
+	{"school", "institution"},
+}
+
+var mastersThesis = regexp.MustCompile(`(?i)master|m\.?\s?sc|m\.?\s?a\.|diplom|bachelor`)
+
+// InferType changes the type of entries that have obviously been given the
+// wrong one, based on their fields: an @article with a booktitle but no
+// journal is an @inproceedings, a @misc with a school is a thesis, and so
+// on. types are the entry types of the style and their fields; we only
+// change to types that the style knows. Fields are renamed to match the new
+// type (e.g., booktitle to journal) and every change is reported.
+func InferType(types map[string][]string) func(e Element) Element {
+	return func(e Element) Element {
+		t := inferType(e)
+
+		if a, ok := typeAliases[t]; ok {
+			if _, ok := types[t]; !ok {
+				t = a
+			}
+		}
+
+		if t == e.Type {
+			return e
+		}
+
+		fields, ok := types[t]
+		if !ok {
+			return e
+		}
+
+		warnf(e, "changing type from %s to %s", e.Type, t)
+		e.Type = t
+
+		wanted := make(map[string]struct{}, len(fields))
+		for _, f := range fields {
+			wanted[f] = struct{}{}
+		}
+
+		for _, p := range fieldPairs {
+			renameField(e, wanted, p[0], p[1])
+			renameField(e, wanted, p[1], p[0])
+		}
+
+		return e
+	}
+}
+
+// renameField renames from to to if the type wants to but not from and the
+// entry has from but not to.
+func renameField(e Element, wanted map[string]struct{}, to string, from string) {
+	if _, ok := wanted[to]; !ok {
+		return
+	}
+
+	if _, ok := wanted[from]; ok {
+		return
+	}
+
+	val, ok := e.Tags[from]
+	if !ok {
+		return
+	}
+
+	if _, ok := e.Tags[to]; ok {
+		return
+	}
+
+	e.Tags[to] = val
+	delete(e.Tags, from)
+}
+
+// inferType returns the most plausible type for an entry, which may be the
+// type it already has.
+func inferType(e Element) string {
+	has := func(key string) bool {
+		_, ok := e.Tags[key]
+		return ok
+	}
+
+	switch e.Type {
+	case "article":
+		if has("booktitle") && !has("journal") {
+			return "inproceedings"
+		}
+	case "inproceedings", "conference":
+		if has("journal") && !has("booktitle") {
+			return "article"
+		}
+	case "techreport", "report":
+		if has("school") && !has("institution") {
+			return thesisType(e)
+		}
+	case "misc", "unpublished", "online", "electronic", "www", "webpage", "thesis":
+		switch {
+		case has("school"):
+			return thesisType(e)
+		case e.Type == "thesis":
+			return thesisType(e)
+		case has("booktitle"):
+			return "inproceedings"
+		case has("journal"):
+			return "article"
+		case has("institution") && (has("number") || strings.Contains(strings.ToLower(e.Tags["type"]), "report")):
+			return "techreport"
+		case has("isbn") && has("publisher"):
+			return "book"
+		case e.Type == "misc" && has("url") && !has("publisher") && !has("howpublished") && !has("eprint") && !isArXiv(e):
+			// arXiv preprints are @misc on purpose, see NormalizeArXiv
+			return "online"
+		}
+	}
+
+	return e.Type
+}
+
+// isArXiv reports whether an entry has an arXiv identifier anywhere, e.g.,
+// in an "arXiv:2101.01234" note.
+func isArXiv(e Element) bool {
+	if strings.Contains(strings.ToLower(e.Tags["note"]), "arxiv") {
+		return true
+	}
+
+	id, _, _ := findArXiv(e)
+	return id != ""
+}
+
+// thesisType tells a master's thesis from a PhD thesis by its type field.
+func thesisType(e Element) string {
+	if mastersThesis.MatchString(e.Tags["type"]) {
+		return "mastersthesis"
+	}
+
+	return "phdthesis"
+}
@@ -0,0 +1,181 @@
+package bibtex
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// trackingParameters are query parameters that only serve to track where
+// a click came from. Parameters that start with "utm_" are removed as well.
+var trackingParameters = map[string]struct{}{
+	"fbclid":     {},
+	"gclid":      {},
+	"dclid":      {},
+	"msclkid":    {},
+	"mc_cid":     {},
+	"mc_eid":     {},
+	"_ga":        {},
+	"_gl":        {},
+	"casa_token": {},
+}
+
+// acmSessionParameters are session and layout parameters of the old ACM
+// Digital Library. Other sites use the same names for real parameters
+// (e.g., Dropbox's "dl"), so they are only removed from dl.acm.org links.
+var acmSessionParameters = map[string]struct{}{
+	"cfid":       {},
+	"cftoken":    {},
+	"coll":       {},
+	"dl":         {},
+	"preflayout": {},
+}
+
+var (
+	acmLegacyURL = regexp.MustCompile(`^dl\.acm\.org/citation\.cfm$`)
+	acmDOIURL    = regexp.MustCompile(`^dl\.acm\.org/doi/(?:(?:abs|pdf|epdf|full|fullHtml|book)/)?(10\..+)$`)
+	ieeeLegacy   = regexp.MustCompile(`^ieeexplore\.ieee\.org/(?:xpls/abs_all\.jsp|stamp/stamp\.jsp|xpl/articleDetails\.jsp|xpl/freeabs_all\.jsp)$`)
+	ieeeDocument = regexp.MustCompile(`^ieeexplore\.ieee\.org/(?:abstract/)?document/(\d+)/?$`)
+)
+
+var urlUnescape = strings.NewReplacer(`\_`, "_", `\&`, "&", `\#`, "#", `\~`, "~")
+
+// CleanURL canonicalizes the url field: links use https, tracking
+// parameters are removed, and legacy ACM Digital Library and IEEE Xplore
+// links are rewritten to DOI links (or the current form of the link if we
+// don't know the DOI). Legacy ACM links have no current form, so those
+// without a DOI are reported. For biblatex, which prints both, a url that
+// only repeats the DOI is dropped. A urldate is dropped when there is no url
+// or the url is a DOI link, since those do not change.
+func CleanURL(style string) func(e Element) Element {
+	return func(e Element) Element {
+		val, ok := e.Tags["url"]
+		if !ok {
+			dropURLDate(e)
+			return e
+		}
+
+		s, ok := unquote(val)
+		if !ok {
+			return e
+		}
+
+		s = urlUnescape.Replace(strings.TrimSpace(s))
+		if s == "" {
+			delete(e.Tags, "url")
+			dropURLDate(e)
+			return e
+		}
+
+		u, err := url.Parse(s)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "ftp") || strings.ContainsAny(s, " \t\n") {
+			warnf(e, "invalid url %s", val)
+			return e
+		}
+
+		if u.Scheme == "http" {
+			u.Scheme = "https"
+		}
+
+		host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+		path := host + u.Path
+
+		q := u.Query()
+		u.RawQuery = removeTrackingParameters(u.RawQuery, host)
+
+		doi := ""
+		if d, ok := e.Tags["doi"]; ok {
+			doi, _ = unquote(d)
+		}
+
+		// rewrite legacy links
+		switch {
+		case acmLegacyURL.MatchString(path):
+			if d := q.Get("doid"); doi == "" && d != "" {
+				doi = "10.1145/" + d
+			}
+			if doi != "" {
+				u, _ = url.Parse("https://doi.org/" + doi)
+			} else {
+				// the id is not part of the DOI, there is nothing to rewrite to
+				warnf(e, "cannot rewrite legacy ACM url %s without a DOI", val)
+			}
+		case acmDOIURL.MatchString(path):
+			d := normalizeDOI(acmDOIURL.FindStringSubmatch(path)[1])
+			if doi == "" {
+				doi = d
+			}
+			u, _ = url.Parse("https://doi.org/" + d)
+		case ieeeLegacy.MatchString(path) && q.Get("arnumber") != "":
+			if doi != "" {
+				u, _ = url.Parse("https://doi.org/" + doi)
+			} else {
+				u, _ = url.Parse("https://ieeexplore.ieee.org/document/" + q.Get("arnumber"))
+			}
+		case ieeeDocument.MatchString(path):
+			if doi != "" {
+				u, _ = url.Parse("https://doi.org/" + doi)
+			} else {
+				u, _ = url.Parse("https://ieeexplore.ieee.org/document/" + ieeeDocument.FindStringSubmatch(path)[1])
+			}
+		}
+
+		if doi != "" {
+			if _, ok := e.Tags["doi"]; !ok {
+				e.Tags["doi"] = quote(doi)
+			}
+		}
+
+		s = u.String()
+
+		if doiURL.MatchString(s) {
+			dropURLDate(e)
+
+			if style == StyleBibLaTeX && doi != "" && normalizeDOI(s) == normalizeDOI(doi) {
+				delete(e.Tags, "url")
+				return e
+			}
+		}
+
+		e.Tags["url"] = quote(s)
+
+		return e
+	}
+}
+
+// removeTrackingParameters removes the tracking parameters from a raw
+// query. The other parameters are kept exactly as they are, in their order
+// and with their encoding and separators.
+func removeTrackingParameters(rawQuery string, host string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	var kept []string
+
+	for _, pair := range strings.Split(rawQuery, "&") {
+		name, _, _ := strings.Cut(pair, "=")
+		if n, err := url.QueryUnescape(name); err == nil {
+			name = n
+		}
+		name = strings.ToLower(name)
+
+		if _, ok := trackingParameters[name]; ok || strings.HasPrefix(name, "utm_") {
+			continue
+		}
+
+		if _, ok := acmSessionParameters[name]; ok && host == "dl.acm.org" {
+			continue
+		}
+
+		kept = append(kept, pair)
+	}
+
+	return strings.Join(kept, "&")
+}
+
+// dropURLDate removes the access date of the url.
+func dropURLDate(e Element) {
+	delete(e.Tags, "urldate")
+	delete(e.Tags, "lastaccessed")
+}
@@ -0,0 +1,67 @@
+package bibtex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCleanURL(t *testing.T) {
+	tests := []struct {
+		name string
+		in   map[string]string
+		want map[string]string
+		warn string
+	}{
+		{
+			name: "tracking parameters",
+			in:   map[string]string{"url": `"http://example.com/paper?id=1&utm_source=x"`},
+			want: map[string]string{"url": `"https://example.com/paper?id=1"`},
+		},
+		{
+			name: "legacy ACM link with doid",
+			in:   map[string]string{"url": `"https://dl.acm.org/citation.cfm?id=3464298.3476133&doid=3464298.3476133&CFID=1"`},
+			want: map[string]string{"url": `"https://doi.org/10.1145/3464298.3476133"`, "doi": `"10.1145/3464298.3476133"`},
+		},
+		{
+			name: "legacy ACM link with DOI",
+			in:   map[string]string{"url": `"https://dl.acm.org/citation.cfm?id=3476133"`, "doi": `"10.1145/3464298.3476133"`},
+			want: map[string]string{"url": `"https://doi.org/10.1145/3464298.3476133"`, "doi": `"10.1145/3464298.3476133"`},
+		},
+		{
+			name: "legacy ACM link without DOI",
+			in:   map[string]string{"url": `"https://dl.acm.org/citation.cfm?id=3476133&CFID=1&CFTOKEN=2"`},
+			want: map[string]string{"url": `"https://dl.acm.org/citation.cfm?id=3476133"`},
+			warn: "cannot rewrite legacy ACM url",
+		},
+		{
+			name: "IEEE legacy link without DOI",
+			in:   map[string]string{"url": `"http://ieeexplore.ieee.org/stamp/stamp.jsp?arnumber=1234567"`},
+			want: map[string]string{"url": `"https://ieeexplore.ieee.org/document/1234567"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Element{ID: "test", Tags: map[string]string{}}
+			for k, v := range tt.in {
+				e.Tags[k] = v
+			}
+
+			warned := captureWarnings(func() { e = CleanURL(StyleIEEE)(e) })
+
+			for k, v := range tt.want {
+				if got := e.Tags[k]; got != v {
+					t.Errorf("%s = %s, want %s", k, got, v)
+				}
+			}
+
+			if tt.warn == "" && warned != "" {
+				t.Errorf("CleanURL() warned %q", warned)
+			}
+
+			if tt.warn != "" && !strings.Contains(warned, tt.warn) {
+				t.Errorf("CleanURL() warned %q, want a warning with %q", warned, tt.warn)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package bibtex
+
+import (
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed data/venues.yaml
+var defaultVenues []byte
+
+// Venue is a conference or journal with the different ways to write it.
+type Venue struct {
+	// Name is the full name, e.g., "International Middleware Conference".
+	Name string `yaml:"name"`
+	// Short is the abbreviated name as IEEE wants it.
+	Short string `yaml:"short"`
+	// Acronym is the short name of a conference, e.g., "Middleware".
+	Acronym string `yaml:"acronym"`
+	// Publisher and Address are filled in if an entry has none.
+	Publisher string `yaml:"publisher"`
+	Address   string `yaml:"address"`
+	// Patterns are case-insensitive regular expressions for the booktitle
+	// or journal.
+	Patterns []string `yaml:"patterns"`
+
+	patterns []*regexp.Regexp
+}
+
+// Venues is a venue database. Earlier venues take precedence.
+type Venues []*Venue
+
+// DefaultVenues returns the venue database that comes with bibclean.
+func DefaultVenues() Venues {
+	v, err := LoadVenues(defaultVenues)
+	if err != nil {
+		panic(err)
+	}
+
+	return v
+}
+
+// LoadVenues reads a venue database from YAML or JSON (which is also YAML).
+func LoadVenues(data []byte) (Venues, error) {
+	var venues Venues
+
+	if err := yaml.Unmarshal(data, &venues); err != nil {
+		return nil, fmt.Errorf("could not read venues: %w", err)
+	}
+
+	for _, v := range venues {
+		if v.Name == "" {
+			return nil, fmt.Errorf("venue without a name")
+		}
+
+		// the full name always matches
+		v.patterns = append(v.patterns, regexp.MustCompile(`^`+regexp.QuoteMeta(normalizeVenue(v.Name))+`$`))
+
+		for _, p := range v.Patterns {
+			r, err := regexp.Compile(`(?i)` + p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern for venue %s: %w", v.Name, err)
+			}
+			v.patterns = append(v.patterns, r)
+		}
+	}
+
+	return venues, nil
+}
+
+// normalizeVenue prepares a venue for matching: no braces, single spaces,
+// lowercase.
+func normalizeVenue(s string) string {
+	s = strings.NewReplacer("{", "", "}", "", "~", " ", `\&`, "&").Replace(s)
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// Match finds the venue for a booktitle or journal.
+func (venues Venues) Match(s string) *Venue {
+	s = normalizeVenue(s)
+
+	for _, v := range venues {
+		for _, p := range v.patterns {
+			if p.MatchString(s) {
+				return v
+			}
+		}
+	}
+
+	return nil
+}
+
+// CanonicalizeVenues rewrites the booktitle and journal to the canonical
+// name from the venue database, in the form the style prefers: the
+// abbreviated name for IEEE, the full name with acronym and year (e.g.,
+// "International Middleware Conference (Middleware '21)") for ACM, and the
+// full name for biblatex. For IEEE, abbreviations from the lists take
+// precedence over the abbreviated names in the database. Missing publishers
+// and addresses are filled in if the style wants them for the entry type.
+func CanonicalizeVenues(venues Venues, lists AbbreviationLists, style string) func(e Element) Element {
+	return func(e Element) Element {
+		for _, key := range []string{"booktitle", "journal"} {
+			val, ok := e.Tags[key]
+			if !ok {
+				continue
+			}
+
+			s, ok := unquote(val)
+			if !ok {
+				continue
+			}
+
+			v := venues.Match(s)
+			if v == nil {
+				continue
+			}
+
+			e.Tags[key] = quote(v.format(e, key, style))
+
+			if abbrev, ok := lists.venueAbbreviation(s, v); ok && style == StyleIEEE {
+				e.Tags[key] = quote(escapeSpecial(e, key, abbrev))
+			}
+
+			// the same rules as for AddPublisherAddress
+			if v.Publisher != "" && wantsField(e, "publisher") && !hasField(e, "publisher") {
+				e.Tags["publisher"] = quote(v.Publisher)
+			}
+
+			if v.Address != "" && wantsField(e, "address") && !hasField(e, "address") {
+				e.Tags["address"] = quote(v.Address)
+			}
+		}
+
+		return e
+	}
+}
+
+var venueYear = regexp.MustCompile(`^\d{4}$`)
+
+// format writes the venue in the form the style prefers.
+func (v *Venue) format(e Element, key string, style string) string {
+	switch style {
+	case StyleIEEE:
+		if v.Short != "" {
+			return v.Short
+		}
+	case StyleACM:
+		if key != "booktitle" || v.Acronym == "" {
+			break
+		}
+
+		year, _ := unquote(e.Tags["year"])
+		if year == "" {
+			year = e.Tags["year"]
+		}
+
+		if venueYear.MatchString(year) {
+			return fmt.Sprintf("%s (%s '%s)", v.Name, v.Acronym, year[2:])
+		}
+
+		return fmt.Sprintf("%s (%s)", v.Name, v.Acronym)
+	}
+
+	return v.Name
+}
@@ -0,0 +1,103 @@
+package bibtex
+
+import "testing"
+
+func TestCanonicalizeVenues(t *testing.T) {
+	list, err := LoadJabRef([]byte("IEEE Transactions on Parallel and Distributed Systems;IEEE TPDS\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	venues := DefaultVenues()
+
+	tests := []struct {
+		style string
+		lists AbbreviationLists
+		key   string
+		in    string
+		want  string
+	}{
+		{StyleIEEE, nil, "journal", `"IEEE Transactions on Parallel and Distributed Systems"`, `"IEEE Trans. Parallel Distrib. Syst."`},
+		{StyleBibLaTeX, nil, "journal", `"IEEE Trans. Parallel Distrib. Syst."`, `"IEEE Transactions on Parallel and Distributed Systems"`},
+
+		// the abbreviation lists come first
+		{StyleIEEE, AbbreviationLists{list}, "journal", `"IEEE Transactions on Parallel and Distributed Systems"`, `"IEEE TPDS"`},
+		{StyleIEEE, AbbreviationLists{list}, "journal", `"IEEE Trans. Parallel Distrib. Syst."`, `"IEEE TPDS"`},
+		{StyleBibLaTeX, AbbreviationLists{list}, "journal", `"IEEE Trans. Parallel Distrib. Syst."`, `"IEEE Transactions on Parallel and Distributed Systems"`},
+
+		{StyleACM, nil, "journal", `"Some Journal"`, `"Some Journal"`},
+	}
+
+	for _, tt := range tests {
+		e := Element{ID: "test", Type: "article", Tags: map[string]string{tt.key: tt.in, "year": "2021"}}
+
+		if got := CanonicalizeVenues(venues, tt.lists, tt.style)(e).Tags[tt.key]; got != tt.want {
+			t.Errorf("CanonicalizeVenues(%q) of %s = %s, want %s", tt.style, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCanonicalizeVenuesAcronym(t *testing.T) {
+	venues := DefaultVenues()
+
+	for style, want := range map[string]string{
+		StyleIEEE:     `"Int. Middleware Conf."`,
+		StyleACM:      `"International Middleware Conference (Middleware '21)"`,
+		StyleBibLaTeX: `"International Middleware Conference"`,
+	} {
+		for _, in := range []string{`"Proc. of Middleware"`, `"Middleware '21"`, `{ACM/IFIP Middleware}`} {
+			e := Element{ID: "test", Type: "inproceedings", Tags: map[string]string{"booktitle": in, "year": "2021"}}
+
+			if got := CanonicalizeVenues(venues, nil, style)(e).Tags["booktitle"]; got != want {
+				t.Errorf("CanonicalizeVenues(%q) of %s = %s, want %s", style, in, got, want)
+			}
+		}
+	}
+}
+
+func TestCanonicalizeVenuesPublisher(t *testing.T) {
+	venues := DefaultVenues()
+
+	tests := []struct {
+		name     string
+		required []string
+		in       map[string]string
+		want     map[string]string
+	}{
+		{
+			name:     "missing publisher and address",
+			required: []string{"booktitle", "publisher", "address"},
+			in:       map[string]string{"booktitle": `"Middleware '21"`},
+			want:     map[string]string{"publisher": `"Association for Computing Machinery"`, "address": `"New York, NY, USA"`},
+		},
+		{
+			name:     "empty address",
+			required: []string{"booktitle", "publisher", "address"},
+			in:       map[string]string{"booktitle": `"Middleware '21"`, "address": "{}"},
+			want:     map[string]string{"publisher": `"Association for Computing Machinery"`, "address": `"New York, NY, USA"`},
+		},
+		{
+			name:     "existing publisher and address",
+			required: []string{"booktitle", "publisher", "address"},
+			in:       map[string]string{"booktitle": `"Middleware '21"`, "publisher": `"ACM"`, "address": `"Online"`},
+			want:     map[string]string{"publisher": `"ACM"`, "address": `"Online"`},
+		},
+		{
+			name:     "style without address",
+			required: []string{"journal", "publisher"},
+			in:       map[string]string{"journal": `"IEEE Trans. Parallel Distrib. Syst."`},
+			want:     map[string]string{"publisher": `"IEEE"`},
+		},
+	}
+
+	for _, tt := range tests {
+		e := Element{ID: "test", Type: "inproceedings", Tags: tt.in, RequiredKeys: &TagTypes{Required: tt.required}}
+		e = CanonicalizeVenues(venues, nil, StyleBibLaTeX)(e)
+
+		for _, key := range []string{"publisher", "address"} {
+			if e.Tags[key] != tt.want[key] {
+				t.Errorf("%s: %s = %s, want %s", tt.name, key, e.Tags[key], tt.want[key])
+			}
+		}
+	}
+}
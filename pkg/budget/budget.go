@@ -0,0 +1,120 @@
+package budget
+
+import (
+	"maps"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pfandzelter/bibclean/pkg/bibtex"
+)
+
+// Step is a way of shortening an entry, e.g., abbreviating its venue.
+type Step struct {
+	Name  string
+	Apply func(e bibtex.Element) bibtex.Element
+}
+
+// Action is a step that has been applied to an entry and the number of
+// characters it saved.
+type Action struct {
+	ID    string
+	Step  string
+	Saved int
+}
+
+var (
+	command    = regexp.MustCompile(`\\[a-zA-Z]+\s*|\\.`)
+	whitespace = regexp.MustCompile(`\s+`)
+)
+
+// Length estimates the number of characters an entry takes up in the
+// rendered bibliography: the values of the fields it is written with,
+// without braces and commands, separated by commas.
+func Length(e *bibtex.Element) int {
+	var parts []string
+
+	for _, key := range e.RequiredKeys.Required {
+		val := e.Tags[key]
+		if val == "" {
+			continue
+		}
+
+		val = command.ReplaceAllString(val, "")
+		val = strings.NewReplacer(`"`, "", "{", "", "}", "", "~", " ", " # ", "").Replace(val)
+
+		if key == "author" || key == "editor" {
+			val = strings.ReplaceAll(val, " and others", " et al.")
+			val = strings.ReplaceAll(val, " and ", ", ")
+		}
+
+		parts = append(parts, strings.TrimSpace(whitespace.ReplaceAllString(val, " ")))
+	}
+
+	// the label, e.g., "[12] "
+	return len("[00] ") + len([]rune(strings.Join(parts, ", ")))
+}
+
+// Lines estimates the number of lines an entry takes up in a bibliography
+// with the given number of characters per line.
+func Lines(width int) func(e *bibtex.Element) int {
+	return func(e *bibtex.Element) int {
+		return (Length(e) + width - 1) / width
+	}
+}
+
+// Fit shortens entries until their estimated total size is within the
+// budget, where measure gives the size of an entry (Length or Lines). The
+// steps are tried in order, the least aggressive first: each step is
+// applied to one entry after the other, the longest first, and only kept
+// where it makes the entry shorter, until the entries fit. Fit returns what
+// it did and whether the entries fit in the end.
+func Fit(elements []*bibtex.Element, budget int, measure func(e *bibtex.Element) int, steps []Step) ([]Action, bool) {
+	total := 0
+	for _, e := range elements {
+		total += measure(e)
+	}
+
+	var actions []Action
+
+	for _, step := range steps {
+		if total <= budget {
+			break
+		}
+
+		sorted := make([]*bibtex.Element, len(elements))
+		copy(sorted, elements)
+
+		// longest first, ties by ID so that the result is always the same
+		sort.SliceStable(sorted, func(i, j int) bool {
+			li, lj := measure(sorted[i]), measure(sorted[j])
+			if li != lj {
+				return li > lj
+			}
+			return sorted[i].ID < sorted[j].ID
+		})
+
+		for _, e := range sorted {
+			if total <= budget {
+				break
+			}
+
+			c := *e
+			c.Tags = maps.Clone(e.Tags)
+			c = step.Apply(c)
+
+			// a few characters less may not save a line yet, but they add up
+			saved := Length(e) - Length(&c)
+			if saved <= 0 {
+				continue
+			}
+
+			total += measure(&c) - measure(e)
+			*e = c
+
+			actions = append(actions, Action{ID: e.ID, Step: step.Name, Saved: saved})
+		}
+	}
+
+	return actions, total <= budget
+}
@@ -0,0 +1,153 @@
+package budget
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pfandzelter/bibclean/pkg/bibtex"
+)
+
+func bibliography() []*bibtex.Element {
+	return []*bibtex.Element{
+		{
+			ID:           "long",
+			Type:         "inproceedings",
+			RequiredKeys: &bibtex.TagTypes{Required: []string{"author", "title", "booktitle", "year"}},
+			Tags: map[string]string{
+				"author":    `"Alice Smith and Bob Jones and Carol White"`,
+				"title":     `"{Edge} Computing"`,
+				"booktitle": `"Proceedings of the International Conference on Distributed Computing Systems"`,
+				"year":      "2020",
+				"abstract":  `"not written"`,
+			},
+		},
+		{
+			ID:           "short",
+			Type:         "article",
+			RequiredKeys: &bibtex.TagTypes{Required: []string{"author", "title", "journal", "year"}},
+			Tags: map[string]string{
+				"author":  `"Dan Brown"`,
+				"title":   `"Fog"`,
+				"journal": `"Journal"`,
+				"year":    "2021",
+			},
+		},
+	}
+}
+
+var steps = []Step{
+	{"abbreviate venues", bibtex.ShortenBooktitle(nil)},
+	{"truncate author lists", bibtex.TruncateNames(2, 1, false)},
+}
+
+func TestLength(t *testing.T) {
+	e := bibliography()
+
+	// "[00] Dan Brown, Fog, Journal, 2021"
+	if got := Length(e[1]); got != 34 {
+		t.Errorf("Length(short) = %d, want 34", got)
+	}
+
+	// "[00] Alice Smith, Bob Jones, Carol White, Edge Computing, Proceedings
+	// of the International Conference on Distributed Computing Systems, 2020"
+	if got := Length(e[0]); got != 140 {
+		t.Errorf("Length(long) = %d, want 140", got)
+	}
+
+	if got := Lines(60)(e[0]); got != 3 {
+		t.Errorf("Lines(60)(long) = %d, want 3", got)
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name    string
+		budget  int
+		measure func(e *bibtex.Element) int
+		actions []string
+		fits    bool
+		author  string
+	}{
+		{
+			name:    "fits already",
+			budget:  174,
+			measure: Length,
+			fits:    true,
+			author:  `"Alice Smith and Bob Jones and Carol White"`,
+		},
+		{
+			name:    "one step",
+			budget:  150,
+			measure: Length,
+			actions: []string{"long: abbreviate venues"},
+			fits:    true,
+			author:  `"Alice Smith and Bob Jones and Carol White"`,
+		},
+		{
+			name:    "all steps",
+			budget:  130,
+			measure: Length,
+			actions: []string{"long: abbreviate venues", "long: truncate author lists"},
+			fits:    true,
+			author:  `"Alice Smith and others"`,
+		},
+		{
+			name:    "too small",
+			budget:  50,
+			measure: Length,
+			actions: []string{"long: abbreviate venues", "long: truncate author lists"},
+			fits:    false,
+			author:  `"Alice Smith and others"`,
+		},
+		{
+			name:    "lines",
+			budget:  3,
+			measure: Lines(60),
+			actions: []string{"long: abbreviate venues"},
+			fits:    true,
+			author:  `"Alice Smith and Bob Jones and Carol White"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			elements := bibliography()
+
+			actions, fits := Fit(elements, tt.budget, tt.measure, steps)
+
+			var got []string
+			for _, a := range actions {
+				if a.Saved <= 0 {
+					t.Errorf("%s: %s saved %d characters", a.ID, a.Step, a.Saved)
+				}
+				got = append(got, a.ID+": "+a.Step)
+			}
+
+			if !slices.Equal(got, tt.actions) {
+				t.Errorf("Fit() actions = %v, want %v", got, tt.actions)
+			}
+
+			if fits != tt.fits {
+				t.Errorf("Fit() fits = %t, want %t", fits, tt.fits)
+			}
+
+			total := 0
+			for _, e := range elements {
+				total += tt.measure(e)
+			}
+
+			if fits && total > tt.budget {
+				t.Errorf("Fit() fits, but the entries take up %d, more than %d", total, tt.budget)
+			}
+
+			if got := elements[0].Tags["author"]; got != tt.author {
+				t.Errorf("author = %s, want %s", got, tt.author)
+			}
+
+			// nothing to shorten
+			if got := Length(elements[1]); got != 34 {
+				t.Errorf("Length(short) = %d after Fit(), want 34", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+package merge
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/pfandzelter/bibclean/pkg/bibtex"
+)
+
+// IDS_TAG is the field that keeps the keys of merged duplicates. biblatex
+// resolves citations of these keys to the merged entry.
+const IDS_TAG = "ids"
+
+// Cluster is a group of elements that are probably the same work under
+// different keys.
+type Cluster struct {
+	Elements []*bibtex.Element
+	// Score is the lowest similarity that links the elements, from 0 to 1.
+	// Identical DOIs or arXiv identifiers have a score of 1.
+	Score float64
+	// Reasons say how the elements were linked: "doi", "arxiv", or "title".
+	Reasons []string
+}
+
+var (
+	arXivID     = regexp.MustCompile(`(?i)(\d{4}\.\d{4,5}|[a-z-]+(?:\.[a-z]{2})?/\d{7})`)
+	doiPrefix   = regexp.MustCompile(`(?i)^(https?://(dx\.)?doi\.org/|doi:\s*)`)
+	latexMarkup = regexp.MustCompile(`\\[a-zA-Z]+|\\.|[{}"]`)
+	firstYear   = regexp.MustCompile(`\d{4}`)
+)
+
+// FindDuplicates finds elements with different keys that are the same work:
+// elements with the same DOI or arXiv identifier, and elements with the same
+// year and first author whose titles are at least threshold similar (1 is
+// identical, titles are compared without case, punctuation, and markup).
+// Elements with different DOIs are never linked by their titles, e.g., a
+// paper and its extended journal version.
+func FindDuplicates(elements []*bibtex.Element, threshold float64) []Cluster {
+	n := len(elements)
+
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	type link struct {
+		score  float64
+		reason string
+	}
+	links := make(map[int][]link)
+
+	// the DOI of each group, so that titles do not join two different DOIs
+	dois := make([]string, n)
+	for i, e := range elements {
+		dois[i] = doiKey(e)
+	}
+
+	union := func(i, j int, score float64, reason string) {
+		ri, rj := find(i), find(j)
+		if ri != rj {
+			parent[rj] = ri
+			if dois[ri] == "" {
+				dois[ri] = dois[rj]
+			}
+			links[ri] = append(links[ri], links[rj]...)
+			delete(links, rj)
+		}
+		links[ri] = append(links[ri], link{score, reason})
+	}
+
+	// the same identifier means the same work
+	for _, id := range []struct {
+		reason string
+		key    func(e *bibtex.Element) string
+	}{
+		{"doi", doiKey},
+		{"arxiv", arXivKey},
+	} {
+		seen := make(map[string]int)
+		for i, e := range elements {
+			k := id.key(e)
+			if k == "" {
+				continue
+			}
+
+			if j, ok := seen[k]; ok {
+				union(j, i, 1, id.reason)
+				continue
+			}
+			seen[k] = i
+		}
+	}
+
+	// similar titles are only compared within the same year and first author
+	blocks := make(map[string][]int)
+	titles := make([]string, n)
+	for i, e := range elements {
+		titles[i] = normalizeTitle(e.Tags["title"])
+
+		year, author := yearKey(e), firstAuthorKey(e)
+		if year == "" || author == "" || titles[i] == "" {
+			continue
+		}
+
+		blocks[year+"/"+author] = append(blocks[year+"/"+author], i)
+	}
+
+	for _, block := range blocks {
+		for a := 0; a < len(block); a++ {
+			for b := a + 1; b < len(block); b++ {
+				i, j := block[a], block[b]
+				ri, rj := find(i), find(j)
+				if ri == rj || (dois[ri] != "" && dois[rj] != "" && dois[ri] != dois[rj]) {
+					continue
+				}
+
+				if score := similarity(titles[i], titles[j]); score >= threshold {
+					union(i, j, score, "title")
+				}
+			}
+		}
+	}
+
+	groups := make(map[int][]*bibtex.Element)
+	for i, e := range elements {
+		groups[find(i)] = append(groups[find(i)], e)
+	}
+
+	var clusters []Cluster
+	for root, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+
+		c := Cluster{Elements: group, Score: 1}
+		for _, l := range links[root] {
+			c.Score = min(c.Score, l.score)
+			if !contains(c.Reasons, l.reason) {
+				c.Reasons = append(c.Reasons, l.reason)
+			}
+		}
+
+		sort.Slice(c.Elements, func(i, j int) bool {
+			return strings.ToLower(c.Elements[i].ID) < strings.ToLower(c.Elements[j].ID)
+		})
+		sort.Strings(c.Reasons)
+
+		clusters = append(clusters, c)
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		return strings.ToLower(clusters[i].Elements[0].ID) < strings.ToLower(clusters[j].Elements[0].ID)
+	})
+
+	return clusters
+}
+
+// MergeDuplicates merges each cluster into one element: the one whose key
+// is cited, otherwise the one with the most fields. Fields that it does not
+// have are taken from the others, and the keys of the others are added to
+// its ids field. cited are the cited keys, nil if they are not known.
+//
+// Only biblatex resolves citations of the keys in ids (aliases). Without
+// aliases, a cluster is only merged if at most one of its keys is cited,
+// which requires knowing the cited keys. The clusters that are not merged
+// are returned.
+func MergeDuplicates(elements []*bibtex.Element, clusters []Cluster, cited map[string]struct{}, aliases bool) ([]*bibtex.Element, []Cluster) {
+	discarded := make(map[*bibtex.Element]struct{})
+	var skipped []Cluster
+
+	for _, c := range clusters {
+		n := 0
+		for _, e := range c.Elements {
+			if _, ok := cited[e.ID]; ok {
+				n++
+			}
+		}
+
+		if !aliases && (cited == nil || n > 1) {
+			skipped = append(skipped, c)
+			continue
+		}
+
+		keep := c.Elements[0]
+		for _, e := range c.Elements[1:] {
+			_, p := cited[e.ID]
+			_, pk := cited[keep.ID]
+			if (p && !pk) || (p == pk && len(e.Tags) > len(keep.Tags)) {
+				keep = e
+			}
+		}
+
+		var ids []string
+		if s := strings.Trim(keep.Tags[IDS_TAG], `"{}`); s != "" {
+			ids = strings.Split(s, ",")
+		}
+
+		for _, e := range c.Elements {
+			if e == keep {
+				continue
+			}
+
+			if s := strings.Trim(e.Tags[IDS_TAG], `"{}`); s != "" {
+				ids = append(ids, strings.Split(s, ",")...)
+			}
+
+			for tag, value := range e.Tags {
+				if tag == IDS_TAG {
+					continue
+				}
+
+				if v, ok := keep.Tags[tag]; !ok || v == "" {
+					keep.Tags[tag] = value
+				}
+			}
+
+			ids = append(ids, e.ID)
+			discarded[e] = struct{}{}
+		}
+
+		keep.Tags[IDS_TAG] = `"` + strings.Join(ids, ",") + `"`
+	}
+
+	l := make([]*bibtex.Element, 0, len(elements)-len(discarded))
+	for _, e := range elements {
+		if _, ok := discarded[e]; !ok {
+			l = append(l, e)
+		}
+	}
+
+	return l, skipped
+}
+
+func contains(s []string, v string) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+func unquote(val string) string {
+	return strings.TrimSpace(strings.Trim(val, `"{} `))
+}
+
+func doiKey(e *bibtex.Element) string {
+	return strings.ToLower(doiPrefix.ReplaceAllString(unquote(e.Tags["doi"]), ""))
+}
+
+// arXivKey finds the arXiv identifier (without version) in the eprint or,
+// for IEEE, in the note.
+func arXivKey(e *bibtex.Element) string {
+	if eprint, ok := e.Tags["eprint"]; ok {
+		prefix := strings.ToLower(e.Tags["archiveprefix"] + e.Tags["eprinttype"])
+		if prefix == "" || strings.Contains(prefix, "arxiv") {
+			return strings.ToLower(arXivID.FindString(eprint))
+		}
+	}
+
+	if note := e.Tags["note"]; strings.Contains(strings.ToLower(note), "arxiv") {
+		return strings.ToLower(arXivID.FindString(note))
+	}
+
+	return ""
+}
+
+func yearKey(e *bibtex.Element) string {
+	if y := firstYear.FindString(e.Tags["year"]); y != "" {
+		return y
+	}
+
+	return firstYear.FindString(e.Tags["date"])
+}
+
+// firstAuthorKey is the last name of the first author (or editor), in
+// lowercase letters only.
+func firstAuthorKey(e *bibtex.Element) string {
+	names := e.Tags["author"]
+	if names == "" {
+		names = e.Tags["editor"]
+	}
+
+	first := strings.SplitN(latexMarkup.ReplaceAllString(names, ""), " and ", 2)[0]
+
+	var last string
+	if before, _, ok := strings.Cut(first, ","); ok {
+		last = before
+	} else if f := strings.Fields(first); len(f) > 0 {
+		last = f[len(f)-1]
+	}
+
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, last)
+}
+
+// normalizeTitle lowercases a title and removes markup and punctuation.
+func normalizeTitle(s string) string {
+	s = latexMarkup.ReplaceAllString(s, "")
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, s)
+
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// similarity is one minus the edit distance of a and b relative to the
+// longer one.
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+
+	return 1 - float64(prev[len(rb)])/float64(max(len(ra), len(rb)))
+}
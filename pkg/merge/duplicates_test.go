@@ -0,0 +1,197 @@
+package merge
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pfandzelter/bibclean/pkg/bibtex"
+)
+
+func element(id string, tags map[string]string) *bibtex.Element {
+	return &bibtex.Element{ID: id, Type: "article", Tags: tags}
+}
+
+func ids(elements []*bibtex.Element) []string {
+	var l []string
+	for _, e := range elements {
+		l = append(l, e.ID)
+	}
+	return l
+}
+
+func TestFindDuplicates(t *testing.T) {
+	tests := []struct {
+		name     string
+		elements []*bibtex.Element
+		want     [][]string
+		reasons  [][]string
+	}{
+		{
+			name: "near-duplicate titles",
+			elements: []*bibtex.Element{
+				element("b", map[string]string{"title": `"{S}erverless Computing: One Step Forward, Two Steps Back"`, "author": `"Hellerstein, Joseph M. and Faleiro, Jose"`, "year": "2019"}),
+				element("a", map[string]string{"title": `"Serverless computing -- one step forward, two steps back."`, "author": `"J. M. Hellerstein and J. Faleiro"`, "year": `"2019"`}),
+			},
+			want:    [][]string{{"a", "b"}},
+			reasons: [][]string{{"title"}},
+		},
+		{
+			name: "similar titles in different years",
+			elements: []*bibtex.Element{
+				element("a", map[string]string{"title": `"Serverless Computing: One Step Forward, Two Steps Back"`, "author": `"Hellerstein, Joseph M."`, "year": "2019"}),
+				element("b", map[string]string{"title": `"Serverless Computing: One Step Forward, Two Steps Back"`, "author": `"Hellerstein, Joseph M."`, "year": "2018"}),
+			},
+		},
+		{
+			name: "similar titles by different authors",
+			elements: []*bibtex.Element{
+				element("a", map[string]string{"title": `"Edge Computing"`, "author": `"Smith, John"`, "year": "2019"}),
+				element("b", map[string]string{"title": `"Edge Computing"`, "author": `"Doe, Jane"`, "year": "2019"}),
+			},
+		},
+		{
+			name: "different titles",
+			elements: []*bibtex.Element{
+				element("a", map[string]string{"title": `"Edge Computing"`, "author": `"Smith, John"`, "year": "2019"}),
+				element("b", map[string]string{"title": `"Fog Computing"`, "author": `"Smith, John"`, "year": "2019"}),
+			},
+		},
+		{
+			name: "same DOI",
+			elements: []*bibtex.Element{
+				element("a", map[string]string{"title": `"Edge Computing"`, "doi": `"10.1145/1234.5678"`}),
+				element("b", map[string]string{"title": `"On the Edge"`, "doi": `"https://doi.org/10.1145/1234.5678"`}),
+				element("c", map[string]string{"title": `"Edge Computing"`, "doi": `"DOI: 10.1145/1234.5678"`}),
+			},
+			want:    [][]string{{"a", "b", "c"}},
+			reasons: [][]string{{"doi"}},
+		},
+		{
+			name: "different DOIs",
+			elements: []*bibtex.Element{
+				element("a", map[string]string{"title": `"Edge Computing"`, "author": `"Smith, John"`, "year": "2019", "doi": `"10.1145/1234.5678"`}),
+				element("b", map[string]string{"title": `"Edge Computing"`, "author": `"Smith, John"`, "year": "2019", "doi": `"10.1109/1234.5678"`}),
+			},
+		},
+		{
+			name: "different DOIs through an entry without one",
+			elements: []*bibtex.Element{
+				element("a", map[string]string{"title": `"Edge Computing"`, "author": `"Smith, John"`, "year": "2019", "doi": `"10.1145/1234.5678"`}),
+				element("b", map[string]string{"title": `"Edge Computing."`, "author": `"Smith, John"`, "year": "2019"}),
+				element("c", map[string]string{"title": `"Edge Computing"`, "author": `"Smith, John"`, "year": "2019", "doi": `"10.1109/1234.5678"`}),
+			},
+			want:    [][]string{{"a", "b"}},
+			reasons: [][]string{{"title"}},
+		},
+		{
+			name: "same arXiv identifier",
+			elements: []*bibtex.Element{
+				element("a", map[string]string{"title": `"Edge Computing"`, "eprint": `"2101.01234v2"`, "archiveprefix": `"arXiv"`}),
+				element("b", map[string]string{"title": `"Edge Computing"`, "note": `"arXiv:2101.01234"`}),
+			},
+			want:    [][]string{{"a", "b"}},
+			reasons: [][]string{{"arxiv"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clusters := FindDuplicates(tt.elements, 0.9)
+
+			var got, reasons [][]string
+			for _, c := range clusters {
+				got = append(got, ids(c.Elements))
+				reasons = append(reasons, c.Reasons)
+			}
+
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("FindDuplicates() = %v, want %v", got, tt.want)
+			}
+
+			if !slices.EqualFunc(reasons, tt.reasons, slices.Equal) {
+				t.Errorf("FindDuplicates() reasons = %v, want %v", reasons, tt.reasons)
+			}
+		})
+	}
+}
+
+func TestMergeDuplicates(t *testing.T) {
+	tests := []struct {
+		name    string
+		cited   map[string]struct{}
+		aliases bool
+		// keep is the element that is left, "" if nothing is merged
+		keep string
+		ids  string
+	}{
+		{
+			name:    "most fields",
+			aliases: true,
+			keep:    "b",
+			ids:     `"b1,b2,a1,a,c"`,
+		},
+		{
+			name:    "cited",
+			cited:   map[string]struct{}{"c": {}},
+			aliases: true,
+			keep:    "c",
+			ids:     `"a1,a,b1,b2,b"`,
+		},
+		{
+			name:    "several cited with aliases",
+			cited:   map[string]struct{}{"a": {}, "c": {}},
+			aliases: true,
+			keep:    "a",
+			ids:     `"a1,b1,b2,b,c"`,
+		},
+		{
+			name:  "one cited without aliases",
+			cited: map[string]struct{}{"a": {}},
+			keep:  "a",
+			ids:   `"a1,b1,b2,b,c"`,
+		},
+		{
+			name:  "several cited without aliases",
+			cited: map[string]struct{}{"a": {}, "c": {}},
+		},
+		{
+			name: "unknown citations without aliases",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			elements := []*bibtex.Element{
+				element("a", map[string]string{"title": `"Edge Computing"`, "ids": `"a1"`}),
+				element("b", map[string]string{"title": `"Edge Computing"`, "year": "2019", "pages": `"1--10"`, "ids": `{b1,b2}`}),
+				element("c", map[string]string{"title": `"Edge Computing"`, "doi": `"10.1145/1234.5678"`}),
+				element("d", map[string]string{"title": `"Fog Computing"`}),
+			}
+			clusters := []Cluster{{Elements: elements[:3], Score: 1, Reasons: []string{"title"}}}
+
+			merged, skipped := MergeDuplicates(elements, clusters, tt.cited, tt.aliases)
+
+			if tt.keep == "" {
+				if len(merged) != len(elements) || len(skipped) != 1 {
+					t.Errorf("MergeDuplicates() = %v, %d skipped, want nothing merged", ids(merged), len(skipped))
+				}
+				return
+			}
+
+			if want := []string{tt.keep, "d"}; !slices.Equal(ids(merged), want) || len(skipped) != 0 {
+				t.Fatalf("MergeDuplicates() = %v, %d skipped, want %v", ids(merged), len(skipped), want)
+			}
+
+			keep := merged[0]
+			if got := keep.Tags[IDS_TAG]; got != tt.ids {
+				t.Errorf("ids = %s, want %s", got, tt.ids)
+			}
+
+			for _, tag := range []string{"year", "pages", "doi"} {
+				if _, ok := keep.Tags[tag]; !ok {
+					t.Errorf("merged element has no %s", tag)
+				}
+			}
+		})
+	}
+}